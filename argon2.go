@@ -21,8 +21,11 @@
 package argon2
 
 import (
-	"crypto/rand"
 	"crypto/subtle"
+	"fmt"
+	"io"
+	"math"
+	"strings"
 
 	"golang.org/x/crypto/argon2"
 )
@@ -31,14 +34,11 @@ import (
 type Mode uint32
 
 const (
-	// modeArgon2d is added here for completeness, but is not exposed as the
-	// x/crypto library does not expose argon2d as an option.
-	//
-	// modeArgon2d is faster and uses data-depending memory access,
+	// ModeArgon2d is faster and uses data-depending memory access,
 	// which makes it highly resistant against GPU cracking attacks and
 	// suitable for applications with no (!) threats from
 	// side-channel timing attacks (eg. cryptocurrencies).
-	modeArgon2d = iota
+	ModeArgon2d = iota
 
 	// ModeArgon2i uses data-independent memory access, which is
 	// preferred for password hashing and password-based key derivation
@@ -57,7 +57,7 @@ const (
 // or returns "unknown" if `m` does not match one of the constants.
 func (m Mode) String() string {
 	switch m {
-	case modeArgon2d:
+	case ModeArgon2d:
 		return "Argon2d"
 	case ModeArgon2i:
 		return "Argon2i"
@@ -68,6 +68,41 @@ func (m Mode) String() string {
 	}
 }
 
+// MarshalText implements encoding.TextMarshaler, returning the same
+// "Argon2{d,i,id}" string as String.
+func (m Mode) MarshalText() ([]byte, error) {
+	s := m.String()
+	if s == "unknown" {
+		return nil, ErrIncorrectType
+	}
+	return []byte(s), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. See ParseMode.
+func (m *Mode) UnmarshalText(text []byte) error {
+	mode, err := ParseMode(string(text))
+	if err != nil {
+		return err
+	}
+	*m = mode
+	return nil
+}
+
+// ParseMode maps a case-insensitive "argon2{d,i,id}" string, as returned by
+// Mode.String or found in an encoded hash, back to its Mode constant.
+func ParseMode(s string) (Mode, error) {
+	switch strings.ToLower(s) {
+	case "argon2d":
+		return ModeArgon2d, nil
+	case "argon2i":
+		return ModeArgon2i, nil
+	case "argon2id":
+		return ModeArgon2id, nil
+	default:
+		return 0, ErrIncorrectType
+	}
+}
+
 // Version exists for type check purposes. See Config.
 type Version uint32
 
@@ -92,6 +127,42 @@ func (v Version) String() string {
 	}
 }
 
+// MarshalText implements encoding.TextMarshaler, returning the same
+// "{10,13}" string as String.
+func (v Version) MarshalText() ([]byte, error) {
+	s := v.String()
+	if s == "unknown" {
+		return nil, ErrIncorrectType
+	}
+	return []byte(s), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. See ParseVersion.
+func (v *Version) UnmarshalText(text []byte) error {
+	version, err := ParseVersion(string(text))
+	if err != nil {
+		return err
+	}
+	*v = version
+	return nil
+}
+
+// ParseVersion maps a version string back to its Version constant.
+//
+// Accepts the hexadecimal form returned by Version.String ("10", "13"), the
+// decimal form used in encoded hashes ("16", "19") as well as the "0x10" and
+// "1.0" notations.
+func ParseVersion(s string) (Version, error) {
+	switch s {
+	case "10", "16", "0x10", "1.0":
+		return Version10, nil
+	case "13", "19", "0x13", "1.3":
+		return Version13, nil
+	default:
+		return 0, ErrIncorrectType
+	}
+}
+
 // Config contains all configuration parameters for the Argon2 hash function.
 type Config struct {
 	// HashLength specifies the length of the resulting hash in Bytes.
@@ -117,11 +188,20 @@ type Config struct {
 	// Must be > 0.
 	MemoryCost uint32
 
-	// Parallelism specifies the amount of threads to use.
+	// Parallelism specifies the number of lanes the memory is split into,
+	// which by default are computed on as many threads.
 	//
 	// Must be > 0.
 	Parallelism uint8
 
+	// Threads limits the number of goroutines used to compute the lanes.
+	// Unlike Parallelism, it doesn't affect the resulting hash, so a hash
+	// with Parallelism 4 can be computed, or verified, on a single thread
+	// where CPU is constrained.
+	//
+	// 0 uses one goroutine per lane.
+	Threads uint8 `json:",omitempty"`
+
 	// Mode specifies the hashing method used by argon2.
 	//
 	// If you're writing a server and unsure what to choose,
@@ -130,6 +210,11 @@ type Config struct {
 
 	// Version specifies the argon2 version to be used.
 	Version Version
+
+	// Options contains optional hashing inputs. Options are not recorded
+	// in encoded hashes, so must be set again on decoded Raw structs before
+	// verifying.
+	Options *Options `json:"-"`
 }
 
 // DefaultConfig returns a Config struct suitable for most servers. These
@@ -182,31 +267,68 @@ func MemoryConstrainedDefaults() Config {
 	}
 }
 
+// NewSalt returns a salt of Config.SaltLength bytes read from Options.Rand,
+// which defaults to crypto/rand.Reader.
+func (c *Config) NewSalt() ([]byte, error) {
+	salt := make([]byte, c.SaltLength)
+	if _, err := io.ReadFull(c.Options.random(), salt); err != nil {
+		return nil, fmt.Errorf("argon2: generating salt: %w", err)
+	}
+	return salt, nil
+}
+
 // Hash takes a password and optionally a salt and returns an Argon2 hash.
 //
 // If salt is nil an appropriate salt of Config.SaltLength bytes is generated
-// for you.
+// for you, read from Options.Rand.
 func (c *Config) Hash(pwd, salt []byte) (Raw, error) {
 	if pwd == nil {
 		return Raw{}, ErrPwdTooShort
 	}
 
+	c = c.withDefaults()
+	if err := c.validate(pwd, salt); err != nil {
+		return Raw{}, err
+	}
+
 	if salt == nil {
-		salt = make([]byte, c.SaltLength)
-		_, err := rand.Read(salt)
-		if err != nil {
+		var err error
+		if salt, err = c.NewSalt(); err != nil {
 			return Raw{}, err
 		}
 	}
 
-	hash := make([]byte, c.HashLength)
-	switch c.Mode {
-	case ModeArgon2i:
+	// x/crypto only computes version 1.3 hashes, using one goroutine per
+	// lane, and is slower than the in-package core's vector instructions.
+	// Everything else is computed by the core.
+	version := c.Options.version(c.Version)
+	xcrypto := !hasSIMD() && version == Version13 && (c.Threads == 0 || c.Threads >= c.Parallelism) &&
+		!c.Options.needsCore()
+
+	var hash []byte
+	switch {
+	case xcrypto && c.Mode == ModeArgon2i:
 		hash = argon2.Key(pwd, salt, c.TimeCost, c.MemoryCost, c.Parallelism, c.HashLength)
-	case ModeArgon2id:
+	case xcrypto && c.Mode == ModeArgon2id:
 		hash = argon2.IDKey(pwd, salt, c.TimeCost, c.MemoryCost, c.Parallelism, c.HashLength)
-	case modeArgon2d:
-		return Raw{}, ErrModeUnsupported
+	default:
+		p := &params{
+			mode:    c.Mode,
+			version: version,
+			pwd:     pwd,
+			salt:    salt,
+			time:    c.TimeCost,
+			memory:  c.MemoryCost,
+			lanes:   uint32(c.Parallelism),
+			threads: uint32(c.Threads),
+			keyLen:  c.HashLength,
+			opts:    c.Options,
+		}
+		if c.Options != nil {
+			p.secret = c.Options.Secret
+			p.data = c.Options.AssociatedData
+		}
+		hash = deriveKey(p)
 	}
 
 	return Raw{
@@ -216,6 +338,48 @@ func (c *Config) Hash(pwd, salt []byte) (Raw, error) {
 	}, nil
 }
 
+// withDefaults returns a copy of `c`, using Version13 if no Version is set.
+func (c *Config) withDefaults() *Config {
+	if c.Version != 0 {
+		return c
+	}
+	cfg := *c
+	cfg.Version = Version13
+	return &cfg
+}
+
+// Input limits of libargon2, which are also enforced by validate.
+const (
+	minSaltLength = 8
+	maxPwdLength  = math.MaxUint32
+)
+
+// validate checks that `c` can be used to hash `pwd` with `salt`. A nil salt
+// means one of Config.SaltLength bytes will be generated for a new hash, so
+// the minimum salt length is only enforced then. Shorter salts of existing
+// hashes, accepted by earlier releases, still verify.
+func (c *Config) validate(pwd, salt []byte) error {
+	switch {
+	case c.Mode != ModeArgon2d && c.Mode != ModeArgon2i && c.Mode != ModeArgon2id:
+		return ErrIncorrectType
+	case c.Version != Version10 && c.Version != Version13:
+		return ErrIncorrectType
+	case c.HashLength < 4:
+		return ErrOutputTooShort
+	case c.TimeCost < ARGON2_MIN_TIME:
+		return ErrTimeTooSmall
+	case c.Parallelism < 1:
+		return ErrLanesTooFew
+	case uint64(c.MemoryCost) < 8*uint64(c.Parallelism):
+		return ErrMemoryTooLittle
+	case uint64(len(pwd)) > maxPwdLength:
+		return ErrPwdTooLong
+	case salt == nil && c.SaltLength < minSaltLength:
+		return ErrSaltTooShort
+	}
+	return nil
+}
+
 // HashRaw is a helper function around Hash()
 // which automatically generates a salt for you.
 func (c *Config) HashRaw(pwd []byte) (Raw, error) {
@@ -247,10 +411,25 @@ type Raw struct {
 	Config Config
 	Salt   []byte
 	Hash   []byte
+
+	// Wrap names the legacy hash function, if any, whose output was hashed
+	// with argon2 instead of the password itself. See Config.HashWrapped.
+	Wrap string
+	// WrapParams contains the parameters, such as a salt, needed to
+	// re-apply the Wrap function.
+	WrapParams []byte
 }
 
 // Verify returns true if `pwd` matches the hash in `raw` and otherwise false.
+//
+// If `raw` wraps a legacy hash, the legacy hash function is applied to `pwd`
+// first.
 func (raw *Raw) Verify(pwd []byte) (bool, error) {
+	pwd, err := raw.unwrap(pwd)
+	if err != nil {
+		return false, err
+	}
+
 	r, err := raw.Config.Hash(pwd, raw.Salt)
 	if err != nil {
 		return false, err
@@ -258,16 +437,34 @@ func (raw *Raw) Verify(pwd []byte) (bool, error) {
 	return subtle.ConstantTimeCompare(r.Hash, raw.Hash) == 1, nil
 }
 
+// DummyVerify performs the same work as verifying `pwd` against a hash
+// generated with `c` using Raw.Verify, but always returns false.
+//
+// Call it when a user can't be found, so that the time taken to reject the
+// login doesn't reveal whether the user exists.
+func (c *Config) DummyVerify(pwd []byte) (bool, error) {
+	raw := Raw{
+		Config: *c,
+		Salt:   make([]byte, c.SaltLength),
+		Hash:   make([]byte, c.HashLength),
+	}
+	if _, err := raw.Verify(pwd); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
 // VerifyEncoded returns true if `pwd` matches the encoded hash `encoded` and
 // otherwise false.
 //
-// Note: Only supports verifying `argon2i` and `argon2id` hashes. As `x/crypto`
-// doesn't expose the generation of `argon2d` hashing, we can't generate an
-// `argon2d` hash to verify the incoming password against.
+// Besides the standard representation, hashes written by Django, Spring
+// Security, Keycloak and LDAP servers are understood. See DecodeAny.
 //
-// Refer: https://github.com/matthewhartstonge/argon2/issues/80#issuecomment-2679636640
+// Hashes computed with a secret or associated data can't be verified this
+// way, as neither is recorded in the encoded hash. Decode the hash and set
+// Raw.Config.Options before calling Raw.Verify instead.
 func VerifyEncoded(pwd, encoded []byte) (bool, error) {
-	r, err := Decode(encoded)
+	r, err := DecodeAny(encoded)
 	if err != nil {
 		return false, err
 	}
@@ -19,6 +19,7 @@ package argon2_test
 import (
 	"bytes"
 	"errors"
+	"io"
 	"reflect"
 	"testing"
 
@@ -64,16 +65,45 @@ func mustBeTruthy(t *testing.T, name string, obj interface{}) {
 	}
 }
 
+// sequenceReader deterministically generates the bytes 0x00, 0x01, ...,
+// wrapping after 0xff.
+type sequenceReader struct {
+	next byte
+}
+
+func (r *sequenceReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = r.next
+		r.next++
+	}
+	return len(p), nil
+}
+
+// deterministicConfig returns config with salts generated by a
+// sequenceReader.
+func deterministicConfig() argon2.Config {
+	cfg := config
+	cfg.Options = &argon2.Options{Rand: &sequenceReader{}}
+	return cfg
+}
+
 func TestHashRaw(t *testing.T) {
-	r, err := config.HashRaw(password)
+	cfg := deterministicConfig()
+	r, err := cfg.HashRaw(password)
 	mustBeTruthy(t, "r.Config", r.Config)
 	mustBeTruthy(t, "r.Salt", r.Salt)
 	mustBeTruthy(t, "r.Hash", r.Hash)
 	mustBeFalsey(t, "err", err)
+
+	wantSalt := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+	if !bytes.Equal(r.Salt, wantSalt) {
+		t.Errorf("HashRaw() salt = %v, want %v", r.Salt, wantSalt)
+	}
 }
 
 func TestHashEncoded(t *testing.T) {
-	enc, err := config.HashEncoded(password)
+	cfg := deterministicConfig()
+	enc, err := cfg.HashEncoded(password)
 	mustBeTruthy(t, "encoded", enc)
 	mustBeFalsey(t, "err", err)
 
@@ -86,18 +116,93 @@ func TestHashEncoded(t *testing.T) {
 			t.Error("encoded must not contain 0x00")
 		}
 	}
+
+	// Computed by the reference implementation, using the salts
+	// 0x00...0x0f and 0x10...0x1f.
+	want := [][]byte{
+		[]byte("$argon2id$v=19$m=32768,t=1,p=1$AAECAwQFBgcICQoLDA0ODw$3CjPy6pyUZBujrCkIH+QMV0IUUWC4Zggi/m9sk731mo"),
+		[]byte("$argon2id$v=19$m=32768,t=1,p=1$EBESExQVFhcYGRobHB0eHw$7LLdQa/tGei8zc/YPw3CIKWWwkC3pARKWFAiypiwOqI"),
+	}
+	for i, w := range want {
+		if i > 0 {
+			enc, err = cfg.HashEncoded(password)
+			mustBeFalsey(t, "err", err)
+		}
+		if !bytes.Equal(enc, w) {
+			t.Logf("ref: %s", w)
+			t.Logf("act: %s", enc)
+			t.Errorf("encoded strings do not match for hash %d", i)
+		}
+	}
+}
+
+func TestHashRandError(t *testing.T) {
+	cfg := config
+	cfg.Options = &argon2.Options{Rand: bytes.NewReader([]byte("short"))}
+
+	_, err := cfg.HashRaw(password)
+	if !errors.Is(err, io.ErrUnexpectedEOF) || err == io.ErrUnexpectedEOF {
+		t.Errorf("HashRaw() error = %v, want %v wrapped", err, io.ErrUnexpectedEOF)
+	}
 }
 
-func TestHashEncodedArgon2dError(t *testing.T) {
+func TestNewSalt(t *testing.T) {
+	cfg := deterministicConfig()
+	cfg.SaltLength = 4
+
+	salt, err := cfg.NewSalt()
+	mustBeFalsey(t, "err", err)
+	if want := []byte{0, 1, 2, 3}; !bytes.Equal(salt, want) {
+		t.Errorf("NewSalt() = %v, want %v", salt, want)
+	}
+
+	cfg.Options = nil
+	salt, err = cfg.NewSalt()
+	mustBeFalsey(t, "err", err)
+	if len(salt) != 4 {
+		t.Errorf("len(NewSalt()) = %d, want 4", len(salt))
+	}
+}
+
+func TestHashEncodedArgon2d(t *testing.T) {
 	cfg := argon2.DefaultConfig()
-	cfg.Mode = 0 // argon2d
+	cfg.Mode = argon2.ModeArgon2d
+
+	encoded, err := cfg.HashEncoded(password)
+	mustBeFalsey(t, "err1", err)
+	if !bytes.HasPrefix(encoded, []byte("$argon2d$v=19$")) {
+		t.Errorf("HashEncoded() = %s, want an argon2d hash", encoded)
+	}
 
-	_, err := cfg.HashEncoded(password)
-	if err == nil {
-		t.Error("HashEncoded() should have returned an error")
+	ok, err := argon2.VerifyEncoded(password, encoded)
+	mustBeTruthy(t, "ok", ok)
+	mustBeFalsey(t, "err2", err)
+}
+
+func TestHashSecret(t *testing.T) {
+	cfg := config
+	cfg.Options = &argon2.Options{
+		Secret:         []byte("pepper"),
+		AssociatedData: []byte("user-id"),
 	}
-	if !errors.Is(err, argon2.ErrModeUnsupported) {
-		t.Errorf("HashEncoded() should have returned ErrModeUnsupported")
+
+	r, err := cfg.Hash(password, salt)
+	mustBeFalsey(t, "err", err)
+	if bytes.Equal(r.Hash, expectedHash) {
+		t.Error("Hash() with a secret should differ from the hash without one")
+	}
+
+	// Neither the secret nor the associated data is encoded, so decoded
+	// hashes only verify once the options are set again.
+	decoded, err := argon2.Decode(r.Encode())
+	mustBeFalsey(t, "err", err)
+	if ok, _ := decoded.Verify(password); ok {
+		t.Error("Verify() without the secret should fail")
+	}
+
+	decoded.Config.Options = cfg.Options
+	if ok, _ := decoded.Verify(password); !ok {
+		t.Error("Verify() with the secret should succeed")
 	}
 }
 
@@ -124,6 +229,170 @@ func TestHashWithSalt(t *testing.T) {
 	}
 }
 
+func TestHashThreads(t *testing.T) {
+	cfg := argon2.MemoryConstrainedDefaults()
+	want, err := cfg.Hash(password, salt)
+	mustBeFalsey(t, "err", err)
+
+	for _, threads := range []uint8{1, 2, 3, 4, 8} {
+		cfg.Threads = threads
+		got, err := cfg.Hash(password, salt)
+		mustBeFalsey(t, "err", err)
+		if !bytes.Equal(got.Hash, want.Hash) {
+			t.Errorf("Threads=%d: Hash() = %x, want %x", threads, got.Hash, want.Hash)
+		}
+	}
+}
+
+func TestHashProgress(t *testing.T) {
+	cfg := argon2.Config{
+		HashLength:  32,
+		TimeCost:    2,
+		MemoryCost:  256,
+		Parallelism: 3,
+		Mode:        argon2.ModeArgon2id,
+		Version:     argon2.Version13,
+	}
+	want, err := cfg.Hash(password, salt)
+	mustBeFalsey(t, "err", err)
+
+	var reports []argon2.Progress
+	cfg.Options = &argon2.Options{
+		Progress: func(p argon2.Progress) {
+			reports = append(reports, p)
+		},
+	}
+	got, err := cfg.Hash(password, salt)
+	mustBeFalsey(t, "err", err)
+	if !bytes.Equal(got.Hash, want.Hash) {
+		t.Errorf("Hash() with progress = %x, want %x", got.Hash, want.Hash)
+	}
+
+	const total = 2 * 4 * 3
+	if len(reports) != total {
+		t.Fatalf("got %d progress reports, want %d", len(reports), total)
+	}
+
+	seen := map[[3]uint32]bool{}
+	for i, p := range reports {
+		if p.Completed != uint32(i+1) || p.Total != total {
+			t.Errorf("report %d: got %d/%d completed, want %d/%d", i, p.Completed, p.Total, i+1, total)
+		}
+
+		segment := [3]uint32{p.Pass, p.Slice, p.Lane}
+		if seen[segment] || p.Pass >= 2 || p.Slice >= 4 || p.Lane >= 3 {
+			t.Errorf("report %d: unexpected segment %v", i, segment)
+		}
+		seen[segment] = true
+	}
+}
+
+func TestHashVersion10(t *testing.T) {
+	cfg := argon2.Config{
+		HashLength:  32,
+		TimeCost:    3,
+		MemoryCost:  256,
+		Parallelism: 4,
+		Mode:        argon2.ModeArgon2i,
+		Version:     argon2.Version10,
+		Options:     &argon2.Options{StrictVersion10: true},
+	}
+
+	// Computed by the reference implementation.
+	want := []byte("$argon2i$v=16$m=256,t=3,p=4$c29tZXNhbHRzb21lc2FsdA$AJ8CYMqmjnw/XHeJXxfAgVhK4EEH8ex/iNHzAyVzbJg")
+
+	r, err := cfg.Hash(password, []byte("somesaltsomesalt"))
+	mustBeFalsey(t, "err", err)
+	if enc := r.Encode(); !bytes.Equal(enc, want) {
+		t.Logf("ref: %s", want)
+		t.Logf("act: %s", enc)
+		t.Error("encoded strings do not match")
+	}
+}
+
+func TestHashVersionZero(t *testing.T) {
+	cfg := config
+	cfg.Version = 0
+
+	r, err := cfg.Hash(password, salt)
+	mustBeFalsey(t, "err", err)
+	if r.Config.Version != argon2.Version13 {
+		t.Errorf("Version = %v, want %v", r.Config.Version, argon2.Version13)
+	}
+	if enc := r.Encode(); !bytes.Equal(enc, expectedEncoded) {
+		t.Errorf("Encode() = %s, want %s", enc, expectedEncoded)
+	}
+}
+
+func TestVerifyVersion10(t *testing.T) {
+	// Earlier releases computed Version10 hashes as version 1.3, which is
+	// still the default.
+	legacy := []byte("$argon2id$v=16$m=32768,t=1,p=1$c2FsdHNhbHQ$i3ZCXD8RMwu4akQl0xCL9L3ZJjV0lIutsAO27+vSS5s")
+	ok, err := argon2.VerifyEncoded(password, legacy)
+	if err != nil || !ok {
+		t.Errorf("VerifyEncoded() of a version 1.3 hash = %v, %v, want true, nil", ok, err)
+	}
+
+	// Hashes computed using version 1.0, by the reference implementation,
+	// need Options.StrictVersion10.
+	r, err := argon2.Decode([]byte("$argon2i$v=16$m=256,t=3,p=4$c29tZXNhbHRzb21lc2FsdA$AJ8CYMqmjnw/XHeJXxfAgVhK4EEH8ex/iNHzAyVzbJg"))
+	mustBeFalsey(t, "err", err)
+	if ok, err := r.Verify(password); err != nil || ok {
+		t.Errorf("Verify() of a version 1.0 hash = %v, %v, want false, nil", ok, err)
+	}
+	r.Config.Options = &argon2.Options{StrictVersion10: true}
+	if ok, err := r.Verify(password); err != nil || !ok {
+		t.Errorf("Verify() of a version 1.0 hash with StrictVersion10 = %v, %v, want true, nil", ok, err)
+	}
+}
+
+func TestHashInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *argon2.Config)
+		wantErr error
+	}{
+		{name: "unknown mode", modify: func(c *argon2.Config) { c.Mode = 42 }, wantErr: argon2.ErrIncorrectType},
+		{name: "unknown version", modify: func(c *argon2.Config) { c.Version = 0x12 }, wantErr: argon2.ErrIncorrectType},
+		{name: "hash too short", modify: func(c *argon2.Config) { c.HashLength = 3 }, wantErr: argon2.ErrOutputTooShort},
+		{name: "zero time cost", modify: func(c *argon2.Config) { c.TimeCost = 0 }, wantErr: argon2.ErrTimeTooSmall},
+		{name: "zero parallelism", modify: func(c *argon2.Config) { c.Parallelism = 0 }, wantErr: argon2.ErrLanesTooFew},
+		{name: "zero memory cost", modify: func(c *argon2.Config) { c.MemoryCost = 0 }, wantErr: argon2.ErrMemoryTooLittle},
+		{name: "memory cost below 8 blocks per lane", modify: func(c *argon2.Config) { c.MemoryCost, c.Parallelism = 15, 2 }, wantErr: argon2.ErrMemoryTooLittle},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config
+			tt.modify(&cfg)
+
+			_, err := cfg.Hash(password, salt)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHashSaltTooShort(t *testing.T) {
+	cfg := config
+	cfg.SaltLength = 7
+	if _, err := cfg.HashRaw(password); !errors.Is(err, argon2.ErrSaltTooShort) {
+		t.Errorf("HashRaw() error = %v, want %v", err, argon2.ErrSaltTooShort)
+	}
+	if _, err := argon2.GenSalt(cfg); !errors.Is(err, argon2.ErrSaltTooShort) {
+		t.Errorf("GenSalt() error = %v, want %v", err, argon2.ErrSaltTooShort)
+	}
+
+	// Hashes with short salts, created by earlier releases, still verify.
+	r, err := config.Hash(password, []byte("7 bytes"))
+	mustBeFalsey(t, "err", err)
+	ok, err := argon2.VerifyEncoded(password, r.Encode())
+	if err != nil || !ok {
+		t.Errorf("VerifyEncoded() of a 7 byte salt = %v, %v, want true, nil", ok, err)
+	}
+}
+
 func TestVerifyRaw(t *testing.T) {
 	r, err := config.HashRaw(password)
 	mustBeTruthy(t, "r.Config", r.Config)
@@ -146,6 +415,70 @@ func TestVerifyEncoded(t *testing.T) {
 	mustBeFalsey(t, "err2", err)
 }
 
+func TestDummyVerify(t *testing.T) {
+	ok, err := config.DummyVerify(password)
+	mustBeFalsey(t, "err", err)
+	if ok {
+		t.Error("DummyVerify() should never succeed")
+	}
+
+	_, err = config.DummyVerify(nil)
+	if !errors.Is(err, argon2.ErrPwdTooShort) {
+		t.Errorf("got %v, want %v", err, argon2.ErrPwdTooShort)
+	}
+}
+
+// fuzzLimits is the policy FuzzVerifyEncoded applies before verifying, so
+// that fuzzed hashes can't request unbounded memory or time.
+var fuzzLimits = argon2.Config{
+	HashLength:  1024,
+	TimeCost:    4,
+	MemoryCost:  1024,
+	Parallelism: 8,
+}
+
+func FuzzVerifyEncoded(f *testing.F) {
+	f.Add(password, expectedEncoded)
+	f.Add(password, []byte("argon2$argon2i$v=19$m=16,t=2,p=1$MmpQV3BIRTVnOVZHOFBISQ$VDE34WMQOVG4X6Sqje0gkg"))
+	f.Add(password, []byte("{ARGON2}$argon2d$v=16$m=64,t=1,p=1$c29tZXNhbHRzb21lc2FsdA$5pmHh4EEhS4RQYB+LJxMWX8j0iGllZ9RW0waB1CuKu4"))
+	f.Add([]byte("s3cr3t"), []byte(keycloakCredential))
+
+	f.Fuzz(func(t *testing.T, pwd, encoded []byte) {
+		r, err := argon2.DecodeAny(encoded)
+		if err != nil {
+			return
+		}
+		c := r.Config
+		if r.Wrap != "" || c.HashLength > fuzzLimits.HashLength || c.TimeCost > fuzzLimits.TimeCost ||
+			c.MemoryCost > fuzzLimits.MemoryCost || c.Parallelism > fuzzLimits.Parallelism {
+			return
+		}
+
+		ok, err := argon2.VerifyEncoded(pwd, encoded)
+		if ok && err != nil {
+			t.Fatalf("VerifyEncoded() = %v, %v", ok, err)
+		}
+
+		// Hashing pwd with the decoded parameters must produce a hash
+		// which pwd verifies against, unless the parameters are invalid,
+		// in which case verifying must have failed with the same error.
+		fresh, hashErr := c.Hash(pwd, r.Salt)
+		if !errors.Is(err, hashErr) {
+			t.Fatalf("VerifyEncoded() error = %v, but Hash() error = %v", err, hashErr)
+		}
+		if hashErr != nil {
+			return
+		}
+
+		if ok, err := argon2.VerifyEncoded(pwd, fresh.Encode()); !ok || err != nil {
+			t.Errorf("VerifyEncoded(Hash()) = %v, %v, want true, nil", ok, err)
+		}
+		if ok != bytes.Equal(fresh.Hash, r.Hash) {
+			t.Errorf("VerifyEncoded() = %v, but hashes equal = %v", ok, !ok)
+		}
+	})
+}
+
 func TestSecureZeroMemory(t *testing.T) {
 	pwd := append(make([]byte, 0, len(password)), password...)
 
@@ -179,6 +512,14 @@ func BenchmarkRecommendedDefaults(b *testing.B) {
 	}
 }
 
+func BenchmarkMemoryConstrainedDefaultsSingleThread(b *testing.B) {
+	cfg := argon2.MemoryConstrainedDefaults()
+	cfg.Threads = 1
+	for i := 0; i < b.N; i++ {
+		_, _ = cfg.Hash(password, salt)
+	}
+}
+
 func BenchmarkVerify(b *testing.B) {
 	r, err := config.Hash(password, salt)
 	if err != nil {
@@ -215,6 +556,29 @@ func BenchmarkDecode(b *testing.B) {
 	}
 }
 
+func BenchmarkEncodeBinary(b *testing.B) {
+	r, err := config.Hash(password, salt)
+	if err != nil {
+		b.Error(err)
+	}
+
+	b.SetBytes(int64(len(expectedBinary)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = r.EncodeBinary()
+	}
+}
+
+func BenchmarkDecodeBinary(b *testing.B) {
+	b.SetBytes(int64(len(expectedBinary)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = argon2.DecodeBinary(expectedBinary)
+	}
+}
+
 func BenchmarkSecureZeroMemory16(b *testing.B) {
 	const numBytes int = 16
 	buf := make([]byte, numBytes)
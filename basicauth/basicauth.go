@@ -0,0 +1,255 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package basicauth provides net/http middleware authenticating requests
+// using HTTP Basic authentication against argon2 hashed passwords.
+package basicauth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/matthewhartstonge/argon2"
+)
+
+// LookupFunc returns the encoded argon2 hash stored for `user`, or false if
+// the user doesn't exist.
+type LookupFunc func(ctx context.Context, user string) (encoded []byte, ok bool, err error)
+
+// Config configures an Authenticator.
+type Config struct {
+	// Realm is sent to clients in the WWW-Authenticate challenge.
+	Realm string
+
+	// CacheTTL specifies how long a successful verification is cached for,
+	// sparing clients that send credentials with every request the cost of
+	// rehashing their password each time. Zero disables caching.
+	CacheTTL time.Duration
+
+	// MaxConcurrent limits the number of concurrent password verifications,
+	// each of which allocates the MemoryCost of the hash being verified.
+	// Requests wait for a free slot until their context is done. Zero means
+	// unlimited.
+	MaxConcurrent int
+
+	// Dummy configures the dummy verification performed for unknown users,
+	// so their requests take as long as those of existing users. It should
+	// match the Config your passwords are hashed with. The zero value uses
+	// argon2.DefaultConfig, while invalid configs are rejected by New.
+	Dummy argon2.Config
+}
+
+// DefaultConfig returns a Config caching verifications for a minute, with
+// at most 4 concurrent verifications using argon2.DefaultConfig.
+func DefaultConfig() Config {
+	return Config{
+		Realm:         "Restricted",
+		CacheTTL:      time.Minute,
+		MaxConcurrent: 4,
+		Dummy:         argon2.DefaultConfig(),
+	}
+}
+
+// maxCacheEntries bounds the verification cache.
+const maxCacheEntries = 10000
+
+// Authenticator verifies HTTP Basic credentials. It is safe for concurrent
+// use.
+type Authenticator struct {
+	config Config
+	lookup LookupFunc
+	sem    chan struct{}
+
+	// cacheKey keys the HMAC used to derive cache keys, so that neither
+	// passwords nor unsalted digests of them are held in memory.
+	cacheKey []byte
+	mu       sync.Mutex
+	cache    map[[sha256.Size]byte]time.Time
+}
+
+// New returns an Authenticator looking up users' encoded hashes via
+// `lookup`. It performs one dummy verification, returning its error if
+// config.Dummy is invalid.
+func New(lookup LookupFunc, config Config) (*Authenticator, error) {
+	if config.Dummy == (argon2.Config{}) {
+		config.Dummy = argon2.DefaultConfig()
+	}
+
+	// An invalid Dummy would fail without hashing, rejecting unknown users
+	// faster than existing ones.
+	if _, err := config.Dummy.DummyVerify([]byte{}); err != nil {
+		return nil, err
+	}
+
+	a := &Authenticator{
+		config:   config,
+		lookup:   lookup,
+		cacheKey: make([]byte, 32),
+		cache:    map[[sha256.Size]byte]time.Time{},
+	}
+	if config.MaxConcurrent > 0 {
+		a.sem = make(chan struct{}, config.MaxConcurrent)
+	}
+
+	if _, err := rand.Read(a.cacheKey); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// quoteEscaper escapes a realm for use as an RFC 7235 quoted-string.
+var quoteEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// userKey is the context key under which the authenticated user is stored.
+type userKey struct{}
+
+// User returns the username authenticated by the middleware.
+func User(ctx context.Context) (string, bool) {
+	user, ok := ctx.Value(userKey{}).(string)
+	return user, ok
+}
+
+// Middleware returns a handler which only passes requests with valid Basic
+// credentials on to `next`, challenging all others with a 401 response.
+// The authenticated username is available to `next` via User.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	challenge := `Basic realm="` + quoteEscaper.Replace(a.config.Realm) + `", charset="UTF-8"`
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pwd, ok := r.BasicAuth()
+		if ok {
+			var err error
+			ok, err = a.Authenticate(r.Context(), user, pwd)
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+				return
+			}
+		}
+
+		if !ok {
+			w.Header().Set("WWW-Authenticate", challenge)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, user)))
+	})
+}
+
+// Authenticate returns true if `pwd` is the password of `user`.
+//
+// Unknown users are verified using argon2.Config.DummyVerify, so that the
+// time taken doesn't reveal whether a user exists.
+func (a *Authenticator) Authenticate(ctx context.Context, user, pwd string) (bool, error) {
+	encoded, found, err := a.lookup(ctx, user)
+	if err != nil {
+		return false, err
+	}
+
+	key := a.key(user, pwd, encoded)
+	if found && a.cached(key) {
+		return true, nil
+	}
+
+	if a.sem != nil {
+		select {
+		case a.sem <- struct{}{}:
+			defer func() { <-a.sem }()
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+
+	if !found {
+		// Unknown users are rejected like wrong passwords. New has checked
+		// that Dummy is valid, so this can't fail early.
+		_, _ = a.config.Dummy.DummyVerify([]byte(pwd))
+		return false, nil
+	}
+
+	ok, err := argon2.VerifyEncoded([]byte(pwd), encoded)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	a.store(key)
+	return true, nil
+}
+
+// key derives the cache key for a set of credentials. The encoded hash is
+// included, so that changing a user's password invalidates their entry.
+func (a *Authenticator) key(user, pwd string, encoded []byte) (key [sha256.Size]byte) {
+	mac := hmac.New(sha256.New, a.cacheKey)
+	for _, field := range []string{user, pwd, string(encoded)} {
+		mac.Write([]byte(strconv.Itoa(len(field))))
+		mac.Write([]byte{':'})
+		mac.Write([]byte(field))
+	}
+	copy(key[:], mac.Sum(nil))
+	return key
+}
+
+// cached reports whether `key` was successfully verified within the TTL.
+func (a *Authenticator) cached(key [sha256.Size]byte) bool {
+	if a.config.CacheTTL <= 0 {
+		return false
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	expiry, ok := a.cache[key]
+	if ok && time.Now().After(expiry) {
+		delete(a.cache, key)
+		return false
+	}
+	return ok
+}
+
+// store caches a successful verification of `key`.
+func (a *Authenticator) store(key [sha256.Size]byte) {
+	if a.config.CacheTTL <= 0 {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	if len(a.cache) >= maxCacheEntries {
+		for k, expiry := range a.cache {
+			if now.After(expiry) {
+				delete(a.cache, k)
+			}
+		}
+		if len(a.cache) >= maxCacheEntries {
+			// Still full of live entries, so evict arbitrarily.
+			for k := range a.cache {
+				delete(a.cache, k)
+				break
+			}
+		}
+	}
+	a.cache[key] = now.Add(a.config.CacheTTL)
+}
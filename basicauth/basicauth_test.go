@@ -0,0 +1,221 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package basicauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/matthewhartstonge/argon2"
+)
+
+var config = argon2.Config{
+	HashLength:  32,
+	SaltLength:  16,
+	TimeCost:    1,
+	MemoryCost:  1024,
+	Parallelism: 1,
+	Mode:        argon2.ModeArgon2id,
+	Version:     argon2.Version13,
+}
+
+// store is an in-memory user store.
+type store struct {
+	mu    sync.Mutex
+	users map[string][]byte
+}
+
+func (s *store) set(t *testing.T, user, pwd string) {
+	enc, err := config.HashEncoded([]byte(pwd))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[user] = enc
+}
+
+func (s *store) lookup(_ context.Context, user string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user == "broken" {
+		return nil, false, errors.New("database unavailable")
+	}
+	enc, ok := s.users[user]
+	return enc, ok, nil
+}
+
+func newTestAuthenticator(t *testing.T) (*Authenticator, *store, http.Handler) {
+	s := &store{users: map[string][]byte{}}
+	s.set(t, "alice", "s3cr3t")
+
+	cfg := DefaultConfig()
+	cfg.Realm = `Internal "tools"`
+	cfg.Dummy = config
+
+	a, err := New(s.lookup, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _ := User(r.Context())
+		_, _ = w.Write([]byte(user))
+	}))
+
+	return a, s, h
+}
+
+func TestMiddleware(t *testing.T) {
+	_, _, h := newTestAuthenticator(t)
+
+	tests := []struct {
+		name       string
+		user, pwd  string
+		noAuth     bool
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "valid credentials", user: "alice", pwd: "s3cr3t", wantStatus: http.StatusOK, wantBody: "alice"},
+		{name: "wrong password", user: "alice", pwd: "hunter2", wantStatus: http.StatusUnauthorized},
+		{name: "unknown user", user: "mallory", pwd: "s3cr3t", wantStatus: http.StatusUnauthorized},
+		{name: "no credentials", noAuth: true, wantStatus: http.StatusUnauthorized},
+		{name: "lookup error", user: "broken", pwd: "s3cr3t", wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if !tt.noAuth {
+				req.SetBasicAuth(tt.user, tt.pwd)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+
+			challenge := rec.Header().Get("WWW-Authenticate")
+			if tt.wantStatus == http.StatusUnauthorized {
+				want := `Basic realm="Internal \"tools\"", charset="UTF-8"`
+				if challenge != want {
+					t.Errorf("WWW-Authenticate = %q, want %q", challenge, want)
+				}
+			} else if challenge != "" {
+				t.Errorf("WWW-Authenticate should only be set on 401 responses, got %q", challenge)
+			}
+
+			if tt.wantStatus == http.StatusOK && rec.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestAuthenticateUnknownUserDummy(t *testing.T) {
+	s := &store{users: map[string][]byte{}}
+	s.set(t, "alice", "s3cr3t")
+
+	a, err := New(s.lookup, Config{Realm: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.config.Dummy != argon2.DefaultConfig() {
+		t.Errorf("New() Dummy = %v, want argon2.DefaultConfig()", a.config.Dummy)
+	}
+	h := a.Middleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	for _, user := range []string{"alice", "bob"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(user, "hunter2")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s: status = %d, want %d", user, rec.Code, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestNewInvalidDummy(t *testing.T) {
+	s := &store{users: map[string][]byte{}}
+	dummy := argon2.Config{HashLength: 32, SaltLength: 16, TimeCost: 1, MemoryCost: 1024, Parallelism: 1, Version: 7}
+
+	if _, err := New(s.lookup, Config{Realm: "x", Dummy: dummy}); !errors.Is(err, argon2.ErrIncorrectType) {
+		t.Errorf("New() error = %v, want %v", err, argon2.ErrIncorrectType)
+	}
+}
+
+func TestAuthenticateCache(t *testing.T) {
+	a, s, _ := newTestAuthenticator(t)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if ok, err := a.Authenticate(ctx, "alice", "s3cr3t"); err != nil || !ok {
+			t.Fatalf("Authenticate() = %v, %v, want true, nil", ok, err)
+		}
+	}
+	if ok, _ := a.Authenticate(ctx, "alice", "hunter2"); ok {
+		t.Fatal("Authenticate() with the wrong password should fail")
+	}
+
+	if len(a.cache) != 1 {
+		t.Errorf("cache should hold exactly the one successful verification, has %d entries", len(a.cache))
+	}
+
+	// Changing the password must invalidate the cached verification.
+	s.set(t, "alice", "hunter2")
+	if ok, _ := a.Authenticate(ctx, "alice", "s3cr3t"); ok {
+		t.Error("Authenticate() with a cached, but changed password should fail")
+	}
+
+	// Expired entries must not be used.
+	for k := range a.cache {
+		a.cache[k] = time.Now().Add(-time.Second)
+	}
+	s.set(t, "alice", "s3cr3t")
+	key := a.key("alice", "s3cr3t", s.users["alice"])
+	a.cache[key] = time.Now().Add(-time.Second)
+	if a.cached(key) {
+		t.Error("expired cache entries should not be used")
+	}
+}
+
+func TestAuthenticateConcurrencyLimit(t *testing.T) {
+	a, _, _ := newTestAuthenticator(t)
+
+	// Occupy every verification slot.
+	for i := 0; i < cap(a.sem); i++ {
+		a.sem <- struct{}{}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := a.Authenticate(ctx, "alice", "s3cr3t")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Authenticate() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
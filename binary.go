@@ -0,0 +1,183 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package argon2
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Binary encoding format versions, stored in the leading byte of the output
+// of EncodeBinary. Add a new version, and keep decoding the old layouts, if
+// the layout ever changes.
+const (
+	// binaryFormatV1 encodes the parameters, salt and hash.
+	binaryFormatV1 = 0x01
+	// binaryFormatV2 extends binaryFormatV1 with trailing wrapper fields.
+	binaryFormatV2 = 0x02
+)
+
+// EncodeBinary turns a Raw struct into a compact, versioned binary
+// representation. It is considerably smaller and faster to parse than the
+// stringified representation returned by Encode, at the cost of not being
+// human-readable or portable to other argon2 implementations.
+//
+// The layout is:
+//
+//	1 byte   format version (0x01, or 0x02 if raw wraps a legacy hash)
+//	1 byte   mode
+//	1 byte   argon2 version
+//	uvarint  memory cost
+//	uvarint  time cost
+//	uvarint  parallelism
+//	uvarint  salt length, followed by the salt
+//	uvarint  hash length, followed by the hash
+//
+// Followed by, for format version 0x02:
+//
+//	uvarint  wrap length, followed by the wrap name
+//	uvarint  wrap params length, followed by the wrap params
+//
+// ErrIncorrectType is returned if the mode is unknown or the version doesn't
+// fit in its byte.
+func (raw *Raw) EncodeBinary() ([]byte, error) {
+	c := raw.Config
+	if c.Mode.String() == "unknown" || c.Version > math.MaxUint8 {
+		return nil, ErrIncorrectType
+	}
+
+	// 3 fixed bytes + at most 5 bytes for each of the 5 uvarint encoded
+	// uint32s.
+	buf := make([]byte, 0, 3+5*5+len(raw.Salt)+len(raw.Hash))
+
+	format := byte(binaryFormatV1)
+	if raw.Wrap != "" {
+		format = binaryFormatV2
+	}
+
+	buf = append(buf, format, byte(c.Mode), byte(c.Version))
+	buf = binary.AppendUvarint(buf, uint64(c.MemoryCost))
+	buf = binary.AppendUvarint(buf, uint64(c.TimeCost))
+	buf = binary.AppendUvarint(buf, uint64(c.Parallelism))
+	buf = binary.AppendUvarint(buf, uint64(len(raw.Salt)))
+	buf = append(buf, raw.Salt...)
+	buf = binary.AppendUvarint(buf, uint64(len(raw.Hash)))
+	buf = append(buf, raw.Hash...)
+
+	if format == binaryFormatV2 {
+		buf = binary.AppendUvarint(buf, uint64(len(raw.Wrap)))
+		buf = append(buf, raw.Wrap...)
+		buf = binary.AppendUvarint(buf, uint64(len(raw.WrapParams)))
+		buf = append(buf, raw.WrapParams...)
+	}
+
+	return buf, nil
+}
+
+// DecodeBinary takes a binary encoded argon2 hash, as returned by
+// EncodeBinary, and turns it back into a Raw struct.
+//
+// The returned salt and hash are copied, so `data` can safely be reused by
+// the caller.
+func DecodeBinary(data []byte) (Raw, error) {
+	if len(data) < 3 || (data[0] != binaryFormatV1 && data[0] != binaryFormatV2) {
+		return Raw{}, ErrDecodingFail
+	}
+
+	format := data[0]
+	mode := Mode(data[1])
+	if mode.String() == "unknown" {
+		return Raw{}, ErrIncorrectType
+	}
+
+	v := Version(data[2])
+	data = data[3:]
+
+	var params [3]uint64
+	for i := range params {
+		n, l := binary.Uvarint(data)
+		if l <= 0 {
+			return Raw{}, ErrDecodingFail
+		}
+		params[i] = n
+		data = data[l:]
+	}
+
+	m, t, p := params[0], params[1], params[2]
+	if v == 0 || m == 0 || m > math.MaxUint32 || t == 0 || t > math.MaxUint32 || p == 0 || p > math.MaxUint8 {
+		return Raw{}, ErrDecodingFail
+	}
+
+	salt, data, ok := readBinarySlice(data)
+	if !ok {
+		return Raw{}, ErrDecodingFail
+	}
+
+	hash, data, ok := readBinarySlice(data)
+	if !ok {
+		return Raw{}, ErrDecodingFail
+	}
+
+	var wrap, wrapParams []byte
+	if format == binaryFormatV2 {
+		wrap, data, ok = readBinarySlice(data)
+		if !ok || !validWrapName(wrap) {
+			return Raw{}, ErrDecodingFail
+		}
+
+		// wrap params are optional, so may be zero length.
+		if len(data) > 0 && data[0] == 0 {
+			data = data[1:]
+		} else if wrapParams, data, ok = readBinarySlice(data); !ok {
+			return Raw{}, ErrDecodingFail
+		}
+	}
+
+	if len(data) != 0 {
+		return Raw{}, ErrDecodingFail
+	}
+
+	return Raw{
+		Config: Config{
+			HashLength:  uint32(len(hash)),
+			SaltLength:  uint32(len(salt)),
+			MemoryCost:  uint32(m),
+			TimeCost:    uint32(t),
+			Parallelism: uint8(p),
+			Mode:        mode,
+			Version:     v,
+		},
+		Salt:       salt,
+		Hash:       hash,
+		Wrap:       string(wrap),
+		WrapParams: wrapParams,
+	}, nil
+}
+
+// readBinarySlice reads a uvarint length prefixed, non-empty byte slice from
+// data, returning a copy of it and the remaining bytes.
+func readBinarySlice(data []byte) (b, rest []byte, ok bool) {
+	n, l := binary.Uvarint(data)
+	if l <= 0 || n == 0 || n > math.MaxUint32 || n > uint64(len(data)-l) {
+		return nil, data, false
+	}
+
+	data = data[l:]
+	b = append(make([]byte, 0, n), data[:n]...)
+
+	return b, data[n:], true
+}
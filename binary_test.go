@@ -0,0 +1,219 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package argon2_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/matthewhartstonge/argon2"
+)
+
+var expectedBinary = append(
+	[]byte{0x01, 0x02, 0x13, 0x80, 0x80, 0x02, 0x01, 0x01, 0x08},
+	append(append([]byte("saltsalt"), 0x20), expectedHash...)...,
+)
+
+func TestEncodeBinary(t *testing.T) {
+	r, err := argon2.Decode(expectedEncoded)
+	mustBeFalsey(t, "err", err)
+
+	enc, err := r.EncodeBinary()
+	mustBeFalsey(t, "err", err)
+	if !bytes.Equal(enc, expectedBinary) {
+		t.Logf("ref: %v", expectedBinary)
+		t.Logf("act: %v", enc)
+		t.Error("binary encodings do not match")
+	}
+
+	dec, err := argon2.DecodeBinary(enc)
+	mustBeFalsey(t, "err", err)
+
+	if !reflect.DeepEqual(dec, r) {
+		t.Errorf("DecodeBinary() = %+v, want %+v", dec, r)
+	}
+}
+
+func TestDecodeBinaryError(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   []byte
+		wantErr error
+	}{
+		{
+			name:    "empty",
+			input:   nil,
+			wantErr: argon2.ErrDecodingFail,
+		},
+		{
+			name:    "unknown format version",
+			input:   append([]byte{0x03}, expectedBinary[1:]...),
+			wantErr: argon2.ErrDecodingFail,
+		},
+		{
+			name:    "unknown mode",
+			input:   append([]byte{0x01, 0x03}, expectedBinary[2:]...),
+			wantErr: argon2.ErrIncorrectType,
+		},
+		{
+			name:    "zero version",
+			input:   append([]byte{0x01, 0x02, 0x00}, expectedBinary[3:]...),
+			wantErr: argon2.ErrDecodingFail,
+		},
+		{
+			name:    "zero memory cost",
+			input:   append([]byte{0x01, 0x02, 0x13, 0x00}, expectedBinary[6:]...),
+			wantErr: argon2.ErrDecodingFail,
+		},
+		{
+			name:    "memory cost overflows uint32",
+			input:   append([]byte{0x01, 0x02, 0x13, 0x80, 0x80, 0x80, 0x80, 0x10}, expectedBinary[6:]...),
+			wantErr: argon2.ErrDecodingFail,
+		},
+		{
+			name:    "parallelism overflows uint8",
+			input:   append(append([]byte(nil), expectedBinary[:7]...), append([]byte{0x80, 0x02}, expectedBinary[8:]...)...),
+			wantErr: argon2.ErrDecodingFail,
+		},
+		{
+			name:    "empty salt",
+			input:   append(append([]byte(nil), expectedBinary[:8]...), append([]byte{0x00}, expectedBinary[17:]...)...),
+			wantErr: argon2.ErrDecodingFail,
+		},
+		{
+			name:    "truncated hash",
+			input:   expectedBinary[:len(expectedBinary)-1],
+			wantErr: argon2.ErrDecodingFail,
+		},
+		{
+			name:    "trailing data",
+			input:   append(append([]byte(nil), expectedBinary...), 0x00),
+			wantErr: argon2.ErrDecodingFail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := argon2.DecodeBinary(tt.input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func FuzzDecodeBinary(f *testing.F) {
+	f.Add(expectedBinary)
+	f.Add(expectedBinary[:len(expectedBinary)-1])
+	f.Add([]byte{0x01, 0x01, 0x10, 0x01, 0x01, 0x01, 0x01, 0x00, 0x01, 0x00})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r, err := argon2.DecodeBinary(data)
+		if err != nil {
+			return
+		}
+
+		// uvarints may be non-minimally encoded, so compare the decoded
+		// values rather than the bytes.
+		enc, err := r.EncodeBinary()
+		if err != nil {
+			t.Fatalf("EncodeBinary() error = %v", err)
+		}
+		r2, err := argon2.DecodeBinary(enc)
+		if err != nil {
+			t.Fatalf("DecodeBinary(EncodeBinary()) error = %v", err)
+		}
+		if !reflect.DeepEqual(r, r2) {
+			t.Errorf("DecodeBinary(EncodeBinary()) = %+v, want %+v", r2, r)
+		}
+	})
+}
+
+func TestEncodeBinaryError(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(c *argon2.Config)
+	}{
+		{
+			name:   "unknown mode",
+			mutate: func(c *argon2.Config) { c.Mode = 3 },
+		},
+		{
+			name:   "mode too large",
+			mutate: func(c *argon2.Config) { c.Mode = 0x100 },
+		},
+		{
+			name:   "version too large",
+			mutate: func(c *argon2.Config) { c.Version = 0x113 },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := argon2.Decode(expectedEncoded)
+			mustBeFalsey(t, "err", err)
+			tt.mutate(&r.Config)
+
+			if _, err := r.EncodeBinary(); !errors.Is(err, argon2.ErrIncorrectType) {
+				t.Errorf("EncodeBinary() error = %v, want %v", err, argon2.ErrIncorrectType)
+			}
+		})
+	}
+}
+
+func FuzzEncodeBinary(f *testing.F) {
+	f.Add(uint8(argon2.ModeArgon2id), uint8(argon2.Version13), uint32(65536), uint32(3), uint8(4), []byte("saltsalt"), expectedHash)
+	f.Add(uint8(argon2.ModeArgon2i), uint8(argon2.Version10), uint32(1), uint32(1), uint8(1), []byte{0}, []byte{0})
+
+	f.Fuzz(func(t *testing.T, mode, version uint8, m, tc uint32, p uint8, salt, hash []byte) {
+		r := argon2.Raw{
+			Config: argon2.Config{
+				HashLength:  uint32(len(hash)),
+				SaltLength:  uint32(len(salt)),
+				TimeCost:    tc,
+				MemoryCost:  m,
+				Parallelism: p,
+				Mode:        argon2.Mode(mode),
+				Version:     argon2.Version(version),
+			},
+			Salt: salt,
+			Hash: hash,
+		}
+
+		enc, err := r.EncodeBinary()
+		var got argon2.Raw
+		if err == nil {
+			got, err = argon2.DecodeBinary(enc)
+		}
+		valid := r.Config.Mode.String() != "unknown" && version != 0 && m != 0 && tc != 0 && p != 0 && len(salt) != 0 && len(hash) != 0
+		if !valid {
+			if err == nil {
+				t.Fatalf("DecodeBinary(EncodeBinary()) of invalid input %+v should have returned an error", r)
+			}
+			return
+		}
+
+		if err != nil {
+			t.Fatalf("DecodeBinary() error = %v", err)
+		}
+		if !reflect.DeepEqual(got, r) {
+			t.Errorf("DecodeBinary() = %+v, want %+v", got, r)
+		}
+	})
+}
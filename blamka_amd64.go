@@ -0,0 +1,58 @@
+//go:build amd64 && gc && !purego
+
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package argon2
+
+import "golang.org/x/sys/cpu"
+
+var (
+	useAVX2   = cpu.X86.HasAVX2
+	useAVX512 = cpu.X86.HasAVX512F && cpu.X86.HasAVX512VL
+)
+
+//go:noescape
+func processBlockAVX2(out, in1, in2 *block, xor bool)
+
+//go:noescape
+func processBlockAVX512(out, in1, in2 *block, xor bool)
+
+func processBlock(out, in1, in2 *block) {
+	processBlockSIMD(out, in1, in2, false)
+}
+
+func processBlockXOR(out, in1, in2 *block) {
+	processBlockSIMD(out, in1, in2, true)
+}
+
+// processBlockSIMD computes G using the widest instruction set available.
+func processBlockSIMD(out, in1, in2 *block, xor bool) {
+	switch {
+	case useAVX512:
+		processBlockAVX512(out, in1, in2, xor)
+	case useAVX2:
+		processBlockAVX2(out, in1, in2, xor)
+	default:
+		processBlockGeneric(out, in1, in2, xor)
+	}
+}
+
+// hasSIMD reports whether processBlock uses vector instructions, in which
+// case the core outperforms x/crypto.
+func hasSIMD() bool {
+	return useAVX2 || useAVX512
+}
@@ -0,0 +1,193 @@
+// Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build amd64 && gc && !purego
+
+#include "textflag.h"
+
+// VPSHUFB masks rotating each quadword right by 24 and 16 bits.
+DATA ·rotr24<>+0x00(SB)/8, $0x0201000706050403
+DATA ·rotr24<>+0x08(SB)/8, $0x0a09080f0e0d0c0b
+DATA ·rotr24<>+0x10(SB)/8, $0x0201000706050403
+DATA ·rotr24<>+0x18(SB)/8, $0x0a09080f0e0d0c0b
+GLOBL ·rotr24<>(SB), (NOPTR+RODATA), $32
+
+DATA ·rotr16<>+0x00(SB)/8, $0x0100070605040302
+DATA ·rotr16<>+0x08(SB)/8, $0x09080f0e0d0c0b0a
+DATA ·rotr16<>+0x10(SB)/8, $0x0100070605040302
+DATA ·rotr16<>+0x18(SB)/8, $0x09080f0e0d0c0b0a
+GLOBL ·rotr16<>(SB), (NOPTR+RODATA), $32
+
+// The BlaMka permutation P holds its 16 words in four registers, a, b, c
+// and d, each containing one row of the 4x4 matrix. The G function is
+// applied to all four columns at once, then the rows are rotated so that the
+// diagonals line up as columns.
+
+// BLAMKA_ADD computes a = a + b + 2*lo32(a)*lo32(b).
+#define BLAMKA_ADD(a, b, t) \
+	VPMULUDQ b, a, t; \
+	VPADDQ   b, a, a; \
+	VPADDQ   t, a, a; \
+	VPADDQ   t, a, a
+
+// G_AVX2 applies G to the columns of a, b, c and d. Y14 and Y15 must hold
+// the rotr24 and rotr16 masks.
+#define G_AVX2(a, b, c, d, t) \
+	BLAMKA_ADD(a, b, t);         \
+	VPXOR    a, d, d;            \
+	VPSHUFD  $0xb1, d, d;        \
+	BLAMKA_ADD(c, d, t);         \
+	VPXOR    c, b, b;            \
+	VPSHUFB  Y14, b, b;          \
+	BLAMKA_ADD(a, b, t);         \
+	VPXOR    a, d, d;            \
+	VPSHUFB  Y15, d, d;          \
+	BLAMKA_ADD(c, d, t);         \
+	VPXOR    c, b, b;            \
+	VPADDQ   b, b, t;            \
+	VPSRLQ   $63, b, b;          \
+	VPXOR    t, b, b
+
+// G_AVX512 is G_AVX2 using the AVX-512 rotate instruction.
+#define G_AVX512(a, b, c, d, t) \
+	BLAMKA_ADD(a, b, t);   \
+	VPXOR   a, d, d;       \
+	VPRORQ  $32, d, d;     \
+	BLAMKA_ADD(c, d, t);   \
+	VPXOR   c, b, b;       \
+	VPRORQ  $24, b, b;     \
+	BLAMKA_ADD(a, b, t);   \
+	VPXOR   a, d, d;       \
+	VPRORQ  $16, d, d;     \
+	BLAMKA_ADD(c, d, t);   \
+	VPXOR   c, b, b;       \
+	VPRORQ  $63, b, b
+
+#define DIAGONALIZE(b, c, d) \
+	VPERMQ $0x39, b, b; \
+	VPERMQ $0x4e, c, c; \
+	VPERMQ $0x93, d, d
+
+#define UNDIAGONALIZE(b, c, d) \
+	VPERMQ $0x93, b, b; \
+	VPERMQ $0x4e, c, c; \
+	VPERMQ $0x39, d, d
+
+#define P(G) \
+	G(Y0, Y1, Y2, Y3, Y4);  \
+	DIAGONALIZE(Y1, Y2, Y3); \
+	G(Y0, Y1, Y2, Y3, Y4);  \
+	UNDIAGONALIZE(Y1, Y2, Y3)
+
+// ROW applies P to the 16 consecutive words starting at byte offset off.
+#define ROW(G, off) \
+	VMOVDQU off+0(SP), Y0;  \
+	VMOVDQU off+32(SP), Y1; \
+	VMOVDQU off+64(SP), Y2; \
+	VMOVDQU off+96(SP), Y3; \
+	P(G);                   \
+	VMOVDQU Y0, off+0(SP);  \
+	VMOVDQU Y1, off+32(SP); \
+	VMOVDQU Y2, off+64(SP); \
+	VMOVDQU Y3, off+96(SP)
+
+// COLUMN applies P to the word pairs starting at byte offset off of each
+// of the 8 rows.
+#define COLUMN(G, off) \
+	VMOVDQU     off+0(SP), X0;           \
+	VINSERTI128 $1, off+128(SP), Y0, Y0; \
+	VMOVDQU     off+256(SP), X1;         \
+	VINSERTI128 $1, off+384(SP), Y1, Y1; \
+	VMOVDQU     off+512(SP), X2;         \
+	VINSERTI128 $1, off+640(SP), Y2, Y2; \
+	VMOVDQU     off+768(SP), X3;         \
+	VINSERTI128 $1, off+896(SP), Y3, Y3; \
+	P(G);                                \
+	VMOVDQU      X0, off+0(SP);          \
+	VEXTRACTI128 $1, Y0, off+128(SP);    \
+	VMOVDQU      X1, off+256(SP);        \
+	VEXTRACTI128 $1, Y1, off+384(SP);    \
+	VMOVDQU      X2, off+512(SP);        \
+	VEXTRACTI128 $1, Y2, off+640(SP);    \
+	VMOVDQU      X3, off+768(SP);        \
+	VEXTRACTI128 $1, Y3, off+896(SP)
+
+// PERMUTE applies P to the rows, then the columns, of the block on the
+// stack.
+#define PERMUTE(G) \
+	ROW(G, 0);      \
+	ROW(G, 128);    \
+	ROW(G, 256);    \
+	ROW(G, 384);    \
+	ROW(G, 512);    \
+	ROW(G, 640);    \
+	ROW(G, 768);    \
+	ROW(G, 896);    \
+	COLUMN(G, 0);   \
+	COLUMN(G, 16);  \
+	COLUMN(G, 32);  \
+	COLUMN(G, 48);  \
+	COLUMN(G, 64);  \
+	COLUMN(G, 80);  \
+	COLUMN(G, 96);  \
+	COLUMN(G, 112)
+
+// LOAD_R stores R = in1 ^ in2 on the stack.
+#define LOAD_R \
+	MOVQ    out+0(FP), DI;  \
+	MOVQ    in1+8(FP), SI;  \
+	MOVQ    in2+16(FP), DX; \
+	XORQ    AX, AX;         \
+load:                       \
+	VMOVDQU (SI)(AX*1), Y0; \
+	VPXOR   (DX)(AX*1), Y0, Y0; \
+	VMOVDQU Y0, (SP)(AX*1); \
+	ADDQ    $32, AX;        \
+	CMPQ    AX, $1024;      \
+	JB      load
+
+// STORE writes R ^ P(R) to out, xoring it into the existing contents if xor
+// is set.
+#define STORE \
+	MOVBLZX xor+24(FP), CX;     \
+	XORQ    AX, AX;             \
+store:                          \
+	VMOVDQU (SI)(AX*1), Y0;     \
+	VPXOR   (DX)(AX*1), Y0, Y0; \
+	VPXOR   (SP)(AX*1), Y0, Y0; \
+	TESTQ   CX, CX;             \
+	JZ      noxor;              \
+	VPXOR   (DI)(AX*1), Y0, Y0; \
+noxor:                          \
+	VMOVDQU Y0, (DI)(AX*1);     \
+	ADDQ    $32, AX;            \
+	CMPQ    AX, $1024;          \
+	JB      store;              \
+	VZEROUPPER
+
+// func processBlockAVX2(out, in1, in2 *block, xor bool)
+TEXT ·processBlockAVX2(SB), 0, $1024-25
+	LOAD_R
+	VMOVDQU ·rotr24<>(SB), Y14
+	VMOVDQU ·rotr16<>(SB), Y15
+	PERMUTE(G_AVX2)
+	STORE
+	RET
+
+// func processBlockAVX512(out, in1, in2 *block, xor bool)
+TEXT ·processBlockAVX512(SB), 0, $1024-25
+	LOAD_R
+	PERMUTE(G_AVX512)
+	STORE
+	RET
@@ -0,0 +1,45 @@
+//go:build amd64 && gc && !purego
+
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package argon2
+
+import "testing"
+
+// blockFuncs lists the implementations of G supported by this CPU.
+func blockFuncs() map[string]func(out, in1, in2 *block, xor bool) {
+	funcs := map[string]func(out, in1, in2 *block, xor bool){
+		"generic": processBlockGeneric,
+	}
+	if useAVX2 {
+		funcs["avx2"] = processBlockAVX2
+	}
+	if useAVX512 {
+		funcs["avx512"] = processBlockAVX512
+	}
+	return funcs
+}
+
+// TestDeriveKeyGeneric runs TestDeriveKey using only the pure Go block
+// function.
+func TestDeriveKeyGeneric(t *testing.T) {
+	avx2, avx512 := useAVX2, useAVX512
+	useAVX2, useAVX512 = false, false
+	defer func() { useAVX2, useAVX512 = avx2, avx512 }()
+
+	TestDeriveKey(t)
+}
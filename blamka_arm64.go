@@ -0,0 +1,53 @@
+//go:build arm64 && gc && !purego
+
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package argon2
+
+import "golang.org/x/sys/cpu"
+
+var useNEON = cpu.ARM64.HasASIMD
+
+// processBlockNEON computes G using `t` as scratch space.
+//
+//go:noescape
+func processBlockNEON(out, in1, in2, t *block, xor bool)
+
+func processBlock(out, in1, in2 *block) {
+	processBlockSIMD(out, in1, in2, false)
+}
+
+func processBlockXOR(out, in1, in2 *block) {
+	processBlockSIMD(out, in1, in2, true)
+}
+
+// processBlockSIMD computes G using NEON if available.
+func processBlockSIMD(out, in1, in2 *block, xor bool) {
+	if !useNEON {
+		processBlockGeneric(out, in1, in2, xor)
+		return
+	}
+
+	var t block
+	processBlockNEON(out, in1, in2, &t, xor)
+}
+
+// hasSIMD reports whether processBlock uses vector instructions, in which
+// case the core outperforms x/crypto.
+func hasSIMD() bool {
+	return useNEON
+}
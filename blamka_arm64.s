@@ -0,0 +1,165 @@
+// Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build arm64 && gc && !purego
+
+#include "textflag.h"
+
+// The BlaMka permutation P holds its 16 words in eight registers, two per
+// row of the 4x4 matrix: a in V0 and V1, b in V2 and V3, c in V4 and V5 and
+// d in V6 and V7. The G function is applied to two columns at a time, then
+// the rows are rotated so that the diagonals line up as columns.
+//
+// Older Go assemblers lack XTN and UMULL, so those are encoded as WORDs from
+// the register numbers an and bn of a and b.
+
+// BLAMKA_ADD computes a = a + b + 2*lo32(a)*lo32(b), using V30 and V31.
+// The WORDs are XTN V30.2S, a.2D, XTN V31.2S, b.2D and
+// UMULL V30.2D, V30.2S, V31.2S.
+#define BLAMKA_ADD(a, b, an, bn) \
+	WORD $(0x0ea12800 | ((an)<<5) | 30); \
+	WORD $(0x0ea12800 | ((bn)<<5) | 31); \
+	WORD $0x2ebfc3de;                    \
+	VADD b.D2, a.D2, a.D2;               \
+	VADD V30.D2, a.D2, a.D2;             \
+	VADD V30.D2, a.D2, a.D2
+
+// XOR_ROTR computes d = (d ^ a) >>> n, using V29.
+#define XOR_ROTR(a, d, n) \
+	VEOR a.B16, d.B16, V29.B16; \
+	VSHL $(64-n), V29.D2, d.D2; \
+	VSRI $n, V29.D2, d.D2
+
+// G applies G to the two columns held in a, b, c and d.
+#define G(a, b, c, d, an, bn, cn, dn) \
+	BLAMKA_ADD(a, b, an, bn); \
+	XOR_ROTR(a, d, 32);       \
+	BLAMKA_ADD(c, d, cn, dn); \
+	XOR_ROTR(c, b, 24);       \
+	BLAMKA_ADD(a, b, an, bn); \
+	XOR_ROTR(a, d, 16);       \
+	BLAMKA_ADD(c, d, cn, dn); \
+	XOR_ROTR(c, b, 63)
+
+// BLAMKA applies the permutation P to V0-V7. The diagonals are gathered into
+// V16-V19, while c is rotated by swapping V4 and V5.
+#define BLAMKA \
+	G(V0, V2, V4, V6, 0, 2, 4, 6);     \
+	G(V1, V3, V5, V7, 1, 3, 5, 7);     \
+	VEXT $8, V3.B16, V2.B16, V16.B16;  \
+	VEXT $8, V2.B16, V3.B16, V17.B16;  \
+	VEXT $8, V6.B16, V7.B16, V18.B16;  \
+	VEXT $8, V7.B16, V6.B16, V19.B16;  \
+	G(V0, V16, V5, V18, 0, 16, 5, 18); \
+	G(V1, V17, V4, V19, 1, 17, 4, 19); \
+	VEXT $8, V16.B16, V17.B16, V2.B16; \
+	VEXT $8, V17.B16, V16.B16, V3.B16; \
+	VEXT $8, V19.B16, V18.B16, V6.B16; \
+	VEXT $8, V18.B16, V19.B16, V7.B16
+
+// func processBlockNEON(out, in1, in2, t *block, xor bool)
+TEXT ·processBlockNEON(SB), NOSPLIT, $0-33
+	MOVD out+0(FP), R0
+	MOVD in1+8(FP), R1
+	MOVD in2+16(FP), R2
+	MOVD t+24(FP), R3
+
+	// t = R = in1 ^ in2
+	MOVD R1, R5
+	MOVD R2, R6
+	MOVD R3, R7
+	MOVD $16, R8
+
+load:
+	VLD1.P 64(R5), [V0.B16, V1.B16, V2.B16, V3.B16]
+	VLD1.P 64(R6), [V4.B16, V5.B16, V6.B16, V7.B16]
+	VEOR   V4.B16, V0.B16, V0.B16
+	VEOR   V5.B16, V1.B16, V1.B16
+	VEOR   V6.B16, V2.B16, V2.B16
+	VEOR   V7.B16, V3.B16, V3.B16
+	VST1.P [V0.B16, V1.B16, V2.B16, V3.B16], 64(R7)
+	SUBS   $1, R8, R8
+	BNE    load
+
+	// Apply P to the 8 rows of 16 consecutive words.
+	MOVD R3, R5
+	MOVD $8, R8
+
+rows:
+	VLD1.P 64(R5), [V0.D2, V1.D2, V2.D2, V3.D2]
+	VLD1   (R5), [V4.D2, V5.D2, V6.D2, V7.D2]
+	BLAMKA
+	SUB    $64, R5, R5
+	VST1.P [V0.D2, V1.D2, V2.D2, V3.D2], 64(R5)
+	VST1.P [V4.D2, V5.D2, V6.D2, V7.D2], 64(R5)
+	SUBS   $1, R8, R8
+	BNE    rows
+
+	// Apply P to the 8 columns of word pairs, 128 bytes apart.
+	MOVD R3, R5
+	MOVD $128, R6
+	MOVD $8, R8
+
+columns:
+	MOVD   R5, R7
+	VLD1.P (R7)(R6), [V0.D2]
+	VLD1.P (R7)(R6), [V1.D2]
+	VLD1.P (R7)(R6), [V2.D2]
+	VLD1.P (R7)(R6), [V3.D2]
+	VLD1.P (R7)(R6), [V4.D2]
+	VLD1.P (R7)(R6), [V5.D2]
+	VLD1.P (R7)(R6), [V6.D2]
+	VLD1   (R7), [V7.D2]
+	BLAMKA
+	MOVD   R5, R7
+	VST1.P [V0.D2], (R7)(R6)
+	VST1.P [V1.D2], (R7)(R6)
+	VST1.P [V2.D2], (R7)(R6)
+	VST1.P [V3.D2], (R7)(R6)
+	VST1.P [V4.D2], (R7)(R6)
+	VST1.P [V5.D2], (R7)(R6)
+	VST1.P [V6.D2], (R7)(R6)
+	VST1   [V7.D2], (R7)
+	ADD    $16, R5, R5
+	SUBS   $1, R8, R8
+	BNE    columns
+
+	// out = R ^ P(R), xored into out if xor is set.
+	MOVBU xor+32(FP), R4
+	MOVD  $16, R8
+
+store:
+	VLD1.P 64(R1), [V0.B16, V1.B16, V2.B16, V3.B16]
+	VLD1.P 64(R2), [V4.B16, V5.B16, V6.B16, V7.B16]
+	VLD1.P 64(R3), [V16.B16, V17.B16, V18.B16, V19.B16]
+	VEOR   V4.B16, V0.B16, V0.B16
+	VEOR   V5.B16, V1.B16, V1.B16
+	VEOR   V6.B16, V2.B16, V2.B16
+	VEOR   V7.B16, V3.B16, V3.B16
+	VEOR   V16.B16, V0.B16, V0.B16
+	VEOR   V17.B16, V1.B16, V1.B16
+	VEOR   V18.B16, V2.B16, V2.B16
+	VEOR   V19.B16, V3.B16, V3.B16
+	CBZ    R4, noxor
+	VLD1   (R0), [V4.B16, V5.B16, V6.B16, V7.B16]
+	VEOR   V4.B16, V0.B16, V0.B16
+	VEOR   V5.B16, V1.B16, V1.B16
+	VEOR   V6.B16, V2.B16, V2.B16
+	VEOR   V7.B16, V3.B16, V3.B16
+
+noxor:
+	VST1.P [V0.B16, V1.B16, V2.B16, V3.B16], 64(R0)
+	SUBS   $1, R8, R8
+	BNE    store
+	RET
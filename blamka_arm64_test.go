@@ -0,0 +1,45 @@
+//go:build arm64 && gc && !purego
+
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package argon2
+
+import "testing"
+
+// blockFuncs lists the implementations of G supported by this CPU.
+func blockFuncs() map[string]func(out, in1, in2 *block, xor bool) {
+	funcs := map[string]func(out, in1, in2 *block, xor bool){
+		"generic": processBlockGeneric,
+	}
+	if useNEON {
+		funcs["neon"] = func(out, in1, in2 *block, xor bool) {
+			var t block
+			processBlockNEON(out, in1, in2, &t, xor)
+		}
+	}
+	return funcs
+}
+
+// TestDeriveKeyGeneric runs TestDeriveKey using only the pure Go block
+// function.
+func TestDeriveKeyGeneric(t *testing.T) {
+	neon := useNEON
+	useNEON = false
+	defer func() { useNEON = neon }()
+
+	TestDeriveKey(t)
+}
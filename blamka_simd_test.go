@@ -0,0 +1,66 @@
+//go:build (amd64 || arm64) && gc && !purego
+
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package argon2
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestProcessBlockSIMD(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	randomBlock := func() *block {
+		var b block
+		for i := range b {
+			b[i] = rng.Uint64()
+		}
+		return &b
+	}
+
+	for name, f := range blockFuncs() {
+		if name == "generic" {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				in1, in2, out := randomBlock(), randomBlock(), randomBlock()
+				for _, xor := range []bool{false, true} {
+					want, got := *out, *out
+					processBlockGeneric(&want, in1, in2, xor)
+					f(&got, in1, in2, xor)
+					if got != want {
+						t.Fatalf("xor=%t: got %x, want %x", xor, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkProcessBlock(b *testing.B) {
+	var out, in1, in2 block
+	for name, f := range blockFuncs() {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(out) * 8))
+			for i := 0; i < b.N; i++ {
+				f(&out, &in1, &in2, true)
+			}
+		})
+	}
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/matthewhartstonge/argon2"
+)
+
+// CompatName is the binary name which, like the -compat flag, switches to
+// the argument convention and output of the reference argon2 C CLI.
+const CompatName = "argon2-compat"
+
+// Defaults of the reference CLI.
+const (
+	compatTimeCost    = 3
+	compatLogMemory   = 12
+	compatParallelism = 1
+	compatHashLength  = 32
+	// compatMaxPassLen bounds the password read from stdin.
+	compatMaxPassLen = 128
+	// compatMissingArgs is the exit code returned when no salt is given,
+	// ARGON2_MISSING_ARGS.
+	compatMissingArgs = -30
+)
+
+// errCompatUsage is returned by parseCompatArgs when usage was requested.
+var errCompatUsage = errors.New("usage requested")
+
+// compatConfig holds the options parsed by parseCompatArgs.
+type compatConfig struct {
+	salt        []byte
+	argon       argon2.Config
+	encodedOnly bool
+	rawOnly     bool
+}
+
+// runCompat implements the reference argon2 C CLI:
+//
+//	argon2 salt [-i|-d|-id] [-t iterations] [-m log2(memory in KiB) | -k memory in KiB]
+//	       [-p parallelism] [-l hash length] [-e|-r] [-v (10|13)]
+//
+// reading the password from stdin. It returns the process exit code.
+func runCompat(cmd string, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fatal := func(err error) int {
+		_, _ = fmt.Fprintf(stderr, "Error: %s\n", compatMessage(err))
+		return 1
+	}
+
+	if len(args) < 1 {
+		compatUsage(stdout, cmd)
+		return compatMissingArgs
+	}
+	if args[0] == "-h" {
+		compatUsage(stdout, cmd)
+		return 1
+	}
+
+	// The password is read before the arguments are parsed, as reference
+	// scripts rely on the resulting error precedence.
+	pwd := make([]byte, compatMaxPassLen)
+	n, err := io.ReadFull(stdin, pwd)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return fatal(err)
+	}
+	switch n {
+	case 0:
+		return fatal(errors.New("no password read"))
+	case compatMaxPassLen:
+		return fatal(errors.New("Provided password longer than supported in command line utility")) //nolint:stylecheck // matches the reference CLI.
+	}
+	pwd = pwd[:n]
+	defer argon2.SecureZeroMemory(pwd)
+
+	cfg, err := parseCompatArgs(args)
+	if errors.Is(err, errCompatUsage) {
+		compatUsage(stdout, cmd)
+		return 1
+	}
+	if err != nil {
+		return fatal(err)
+	}
+
+	c := cfg.argon
+	if !cfg.encodedOnly && !cfg.rawOnly {
+		_, _ = fmt.Fprintf(stdout, "Type:\t\t%s\n", c.Mode)
+		_, _ = fmt.Fprintf(stdout, "Iterations:\t%d\n", c.TimeCost)
+		_, _ = fmt.Fprintf(stdout, "Memory:\t\t%d KiB\n", c.MemoryCost)
+		_, _ = fmt.Fprintf(stdout, "Parallelism:\t%d\n", c.Parallelism)
+	}
+
+	start := time.Now()
+	if err := compatValidate(cfg); err != nil {
+		return fatal(err)
+	}
+	raw, err := c.Hash(pwd, cfg.salt)
+	if err != nil {
+		return fatal(err)
+	}
+	elapsed := time.Since(start)
+	encoded := raw.Encode()
+
+	if cfg.encodedOnly {
+		_, _ = fmt.Fprintf(stdout, "%s\n", encoded)
+	}
+	if cfg.rawOnly {
+		_, _ = fmt.Fprintf(stdout, "%x\n", raw.Hash)
+	}
+	if cfg.encodedOnly || cfg.rawOnly {
+		return 0
+	}
+
+	_, _ = fmt.Fprintf(stdout, "Hash:\t\t%x\n", raw.Hash)
+	_, _ = fmt.Fprintf(stdout, "Encoded:\t%s\n", encoded)
+	_, _ = fmt.Fprintf(stdout, "%2.3f seconds\n", elapsed.Seconds())
+
+	ok, err := raw.Verify(pwd)
+	if err != nil {
+		return fatal(err)
+	}
+	if !ok {
+		return fatal(argon2.ErrVerifyMismatch)
+	}
+	_, _ = fmt.Fprintln(stdout, "Verification ok")
+
+	return 0
+}
+
+// parseCompatArgs parses the salt and options following it.
+func parseCompatArgs(args []string) (*compatConfig, error) {
+	cfg := &compatConfig{
+		salt: []byte(args[0]),
+		argon: argon2.Config{
+			HashLength:  compatHashLength,
+			SaltLength:  uint32(len(args[0])),
+			TimeCost:    compatTimeCost,
+			MemoryCost:  1 << compatLogMemory,
+			Parallelism: compatParallelism,
+			Mode:        argon2.ModeArgon2i,
+			Version:     argon2.Version13,
+		},
+	}
+	if uint64(len(args[0])) > math.MaxUint32 {
+		return nil, errors.New("salt is too long")
+	}
+
+	var typesSpecified int
+	var memorySpecified bool
+	for i := 1; i < len(args); i++ {
+		a := args[i]
+
+		// next consumes the value of the option `a`.
+		next := func() (string, error) {
+			if i == len(args)-1 {
+				return "", fmt.Errorf("missing %s argument", a)
+			}
+			i++
+			return args[i], nil
+		}
+
+		switch a {
+		case "-h":
+			return nil, errCompatUsage
+
+		case "-m", "-k":
+			if memorySpecified {
+				return nil, errors.New("-m or -k can only be used once")
+			}
+			memorySpecified = true
+
+			value, err := next()
+			if err != nil {
+				return nil, err
+			}
+			input := compatParseUint(value)
+			if input == 0 || input == math.MaxUint64 || (a == "-m" && input > 32) {
+				return nil, fmt.Errorf("bad numeric input for %s", a)
+			}
+			if a == "-m" {
+				input = 1 << input
+			}
+			cfg.argon.MemoryCost = uint32(min(input, math.MaxUint32))
+
+		case "-t":
+			value, err := next()
+			if err != nil {
+				return nil, err
+			}
+			input := compatParseUint(value)
+			if input == 0 || input > math.MaxUint32 {
+				return nil, errors.New("bad numeric input for -t")
+			}
+			cfg.argon.TimeCost = uint32(input)
+
+		case "-p":
+			value, err := next()
+			if err != nil {
+				return nil, err
+			}
+			input := compatParseUint(value)
+			if input == 0 || input > math.MaxUint8 {
+				return nil, errors.New("bad numeric input for -p")
+			}
+			cfg.argon.Parallelism = uint8(input)
+
+		case "-l":
+			value, err := next()
+			if err != nil {
+				return nil, err
+			}
+			cfg.argon.HashLength = uint32(compatParseUint(value))
+
+		case "-v":
+			value, err := next()
+			if err != nil {
+				return nil, err
+			}
+			switch value {
+			case "10":
+				cfg.argon.Version = argon2.Version10
+				cfg.argon.Options = &argon2.Options{StrictVersion10: true}
+			case "13":
+				cfg.argon.Version = argon2.Version13
+			default:
+				return nil, errors.New("invalid Argon2 version")
+			}
+
+		case "-i":
+			cfg.argon.Mode = argon2.ModeArgon2i
+			typesSpecified++
+		case "-d":
+			cfg.argon.Mode = argon2.ModeArgon2d
+			typesSpecified++
+		case "-id":
+			cfg.argon.Mode = argon2.ModeArgon2id
+			typesSpecified++
+
+		case "-e":
+			cfg.encodedOnly = true
+		case "-r":
+			cfg.rawOnly = true
+
+		default:
+			return nil, errors.New("unknown argument")
+		}
+	}
+
+	if typesSpecified > 1 {
+		return nil, errors.New("cannot specify multiple Argon2 types")
+	}
+	if cfg.encodedOnly && cfg.rawOnly {
+		return nil, errors.New("cannot provide both -e and -r")
+	}
+
+	return cfg, nil
+}
+
+// compatParseUint parses the leading digits of `s` like C's strtoul,
+// returning 0 if there are none and math.MaxUint64 on overflow or for
+// negative numbers.
+func compatParseUint(s string) uint64 {
+	s = strings.TrimLeftFunc(s, unicode.IsSpace)
+	if strings.HasPrefix(s, "-") {
+		return math.MaxUint64
+	}
+	s = strings.TrimPrefix(s, "+")
+
+	var r uint64
+	for i := 0; i < len(s) && '0' <= s[i] && s[i] <= '9'; i++ {
+		d := uint64(s[i] - '0')
+		if r > (math.MaxUint64-d)/10 {
+			return math.MaxUint64
+		}
+		r = r*10 + d
+	}
+	return r
+}
+
+// compatValidate applies the input checks of the reference implementation
+// which the CLI's arguments can fail, in the reference's order. Config.Hash
+// checks the output length and memory too, but after the time cost and
+// lanes, and doesn't check the length of a given salt.
+func compatValidate(cfg *compatConfig) error {
+	switch {
+	case cfg.argon.HashLength < 4:
+		return argon2.ErrOutputTooShort
+	case len(cfg.salt) < 8:
+		return argon2.ErrSaltTooShort
+	case cfg.argon.MemoryCost < 8*uint32(cfg.argon.Parallelism):
+		return argon2.ErrMemoryTooLittle
+	}
+	return nil
+}
+
+// compatMessage returns the error message of `err`, capitalising argon2
+// errors like the reference implementation's argon2_error_message.
+func compatMessage(err error) string {
+	msg := err.Error()
+
+	var argonErr argon2.Error
+	if !errors.As(err, &argonErr) {
+		return msg
+	}
+	r, size := utf8.DecodeRuneInString(msg)
+	return string(unicode.ToUpper(r)) + msg[size:]
+}
+
+// compatUsage prints the reference CLI usage.
+func compatUsage(w io.Writer, cmd string) {
+	_, _ = fmt.Fprintf(w, "Usage:  %s [-h] salt [-i|-d|-id] [-t iterations] "+
+		"[-m log2(memory in KiB) | -k memory in KiB] [-p parallelism] "+
+		"[-l hash length] [-e|-r] [-v (10|13)]\n", cmd)
+	_, _ = fmt.Fprintf(w, "\tPassword is read from stdin\n")
+	_, _ = fmt.Fprintf(w, "Parameters:\n")
+	_, _ = fmt.Fprintf(w, "\tsalt\t\tThe salt to use, at least 8 characters\n")
+	_, _ = fmt.Fprintf(w, "\t-i\t\tUse Argon2i (this is the default)\n")
+	_, _ = fmt.Fprintf(w, "\t-d\t\tUse Argon2d instead of Argon2i\n")
+	_, _ = fmt.Fprintf(w, "\t-id\t\tUse Argon2id instead of Argon2i\n")
+	_, _ = fmt.Fprintf(w, "\t-t N\t\tSets the number of iterations to N (default = %d)\n", compatTimeCost)
+	_, _ = fmt.Fprintf(w, "\t-m N\t\tSets the memory usage of 2^N KiB (default %d)\n", compatLogMemory)
+	_, _ = fmt.Fprintf(w, "\t-k N\t\tSets the memory usage of N KiB (default %d)\n", 1<<compatLogMemory)
+	_, _ = fmt.Fprintf(w, "\t-p N\t\tSets parallelism to N threads (default %d)\n", compatParallelism)
+	_, _ = fmt.Fprintf(w, "\t-l N\t\tSets hash output length to N bytes (default %d)\n", compatHashLength)
+	_, _ = fmt.Fprintf(w, "\t-e\t\tOutput only encoded hash\n")
+	_, _ = fmt.Fprintf(w, "\t-r\t\tOutput only the raw bytes of the hash\n")
+	_, _ = fmt.Fprintf(w, "\t-v (10|13)\tArgon2 version (defaults to the most recent version, currently %x)\n", argon2.Version13)
+	_, _ = fmt.Fprintf(w, "\t-h\t\tPrint %s usage\n", cmd)
+}
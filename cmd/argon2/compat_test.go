@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// The reference CLI's example, from its README.
+const (
+	compatHash    = "45d7ac72e76f242b20b77b9bf9bf9d5915894e669a24e6c6"
+	compatEncoded = "$argon2i$v=19$m=65536,t=2,p=4$c29tZXNhbHQ$RdescudvJCsgt3ub+b+dWRWJTmaaJObG"
+)
+
+func TestRunCompat(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		stdin      string
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name:       "encoded",
+			args:       []string{"somesalt", "-t", "2", "-m", "16", "-p", "4", "-l", "24", "-e"},
+			stdin:      "password",
+			wantStdout: compatEncoded + "\n",
+		},
+		{
+			name:       "raw",
+			args:       []string{"somesalt", "-t", "2", "-k", "65536", "-p", "4", "-l", "24", "-r"},
+			stdin:      "password",
+			wantStdout: compatHash + "\n",
+		},
+		{
+			name:       "version 10",
+			args:       []string{"somesaltsomesalt", "-t", "3", "-k", "256", "-p", "4", "-v", "10", "-e"},
+			stdin:      "password",
+			wantStdout: "$argon2i$v=16$m=256,t=3,p=4$c29tZXNhbHRzb21lc2FsdA$AJ8CYMqmjnw/XHeJXxfAgVhK4EEH8ex/iNHzAyVzbJg\n",
+		},
+		{
+			name:       "argon2id",
+			args:       []string{"somesalt", "-id", "-t", "1", "-k", "8", "-l", "4", "-e"},
+			stdin:      "password",
+			wantStdout: "$argon2id$v=19$m=8,t=1,p=1$c29tZXNhbHQ$",
+		},
+		{
+			name:     "missing salt",
+			stdin:    "password",
+			wantCode: compatMissingArgs,
+		},
+		{
+			name:     "help",
+			args:     []string{"-h"},
+			stdin:    "password",
+			wantCode: 1,
+		},
+		{
+			name:     "help after salt",
+			args:     []string{"somesalt", "-h"},
+			stdin:    "password",
+			wantCode: 1,
+		},
+		{
+			name:       "no password",
+			args:       []string{"somesalt"},
+			wantCode:   1,
+			wantStderr: "Error: no password read\n",
+		},
+		{
+			name:       "password too long",
+			args:       []string{"somesalt"},
+			stdin:      strings.Repeat("a", compatMaxPassLen),
+			wantCode:   1,
+			wantStderr: "Error: Provided password longer than supported in command line utility\n",
+		},
+		{
+			name:       "password read before arguments",
+			args:       []string{"somesalt", "-x"},
+			wantCode:   1,
+			wantStderr: "Error: no password read\n",
+		},
+		{
+			name:       "unknown argument",
+			args:       []string{"somesalt", "-x"},
+			stdin:      "password",
+			wantCode:   1,
+			wantStderr: "Error: unknown argument\n",
+		},
+		{
+			name:       "missing argument",
+			args:       []string{"somesalt", "-t"},
+			stdin:      "password",
+			wantCode:   1,
+			wantStderr: "Error: missing -t argument\n",
+		},
+		{
+			name:       "bad numeric input",
+			args:       []string{"somesalt", "-p", "0"},
+			stdin:      "password",
+			wantCode:   1,
+			wantStderr: "Error: bad numeric input for -p\n",
+		},
+		{
+			name:       "memory twice",
+			args:       []string{"somesalt", "-m", "4", "-k", "16"},
+			stdin:      "password",
+			wantCode:   1,
+			wantStderr: "Error: -m or -k can only be used once\n",
+		},
+		{
+			name:       "multiple types",
+			args:       []string{"somesalt", "-d", "-id"},
+			stdin:      "password",
+			wantCode:   1,
+			wantStderr: "Error: cannot specify multiple Argon2 types\n",
+		},
+		{
+			name:       "encoded and raw",
+			args:       []string{"somesalt", "-e", "-r"},
+			stdin:      "password",
+			wantCode:   1,
+			wantStderr: "Error: cannot provide both -e and -r\n",
+		},
+		{
+			name:       "invalid version",
+			args:       []string{"somesalt", "-v", "12"},
+			stdin:      "password",
+			wantCode:   1,
+			wantStderr: "Error: invalid Argon2 version\n",
+		},
+		{
+			name:       "salt too short",
+			args:       []string{"salt", "-e"},
+			stdin:      "password",
+			wantCode:   1,
+			wantStderr: "Error: Salt is too short\n",
+		},
+		{
+			name:       "output too short",
+			args:       []string{"salt", "-l", "3", "-e"},
+			stdin:      "password",
+			wantCode:   1,
+			wantStderr: "Error: Output is too short\n",
+		},
+		{
+			name:       "memory too little",
+			args:       []string{"somesalt", "-k", "8", "-p", "2", "-e"},
+			stdin:      "password",
+			wantCode:   1,
+			wantStderr: "Error: Memory cost is too small\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := runCompat("argon2", tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+			if code != tt.wantCode {
+				t.Errorf("runCompat() = %d, want %d", code, tt.wantCode)
+			}
+			if !strings.HasPrefix(stdout.String(), tt.wantStdout) {
+				t.Errorf("stdout = %q, want prefix %q", stdout.String(), tt.wantStdout)
+			}
+			if stderr.String() != tt.wantStderr {
+				t.Errorf("stderr = %q, want %q", stderr.String(), tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestRunCompatUsage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	runCompat("argon2", nil, strings.NewReader("password"), &stdout, &stderr)
+
+	want := "Usage:  argon2 [-h] salt [-i|-d|-id] [-t iterations] "
+	if !strings.HasPrefix(stdout.String(), want) {
+		t.Errorf("stdout = %q, want prefix %q", stdout.String(), want)
+	}
+}
+
+func TestRunCompatOutput(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	args := []string{"somesalt", "-t", "2", "-m", "16", "-p", "4", "-l", "24"}
+	if code := runCompat("argon2", args, strings.NewReader("password"), &stdout, &stderr); code != 0 {
+		t.Fatalf("runCompat() = %d, want 0, stderr: %s", code, stderr.String())
+	}
+
+	want := regexp.MustCompile(`^` +
+		`Type:\t\tArgon2i\n` +
+		`Iterations:\t2\n` +
+		`Memory:\t\t65536 KiB\n` +
+		`Parallelism:\t4\n` +
+		`Hash:\t\t` + compatHash + `\n` +
+		`Encoded:\t` + regexp.QuoteMeta(compatEncoded) + `\n` +
+		`\d+\.\d{3} seconds\n` +
+		`Verification ok\n$`)
+	if !want.MatchString(stdout.String()) {
+		t.Errorf("stdout = %q, want match of %s", stdout.String(), want)
+	}
+}
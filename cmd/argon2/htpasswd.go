@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/matthewhartstonge/argon2"
+	"github.com/matthewhartstonge/argon2/htpasswd"
+)
+
+// runHtpasswd implements the `htpasswd` subcommand, managing users in an
+// htpasswd style password file. It returns the process exit code.
+func runHtpasswd(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet(AppName+" htpasswd", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	create := fs.Bool("c", false, "create a new password file, overwriting any existing file.")
+	del := fs.Bool("D", false, "delete the user.")
+	verify := fs.Bool("v", false, "verify the password of the user.")
+	batch := fs.Bool("b", false, "take the password from the command line rather than stdin.")
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "USAGE:\n\t%s htpasswd [-c] [-D|-v] [-b] passwordfile username [password]\n\n", AppName)
+		_, _ = fmt.Fprintf(fs.Output(), "OPTIONS:\n")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	wantArgs := 2
+	if *batch && !*del {
+		wantArgs = 3
+	}
+	if fs.NArg() != wantArgs || (*del && *verify) || (*create && (*del || *verify)) {
+		fs.Usage()
+		return 2
+	}
+
+	path, user := fs.Arg(0), fs.Arg(1)
+	if err := htpasswdCommand(path, user, fs.Arg(2), *create, *del, *verify, *batch, stdin, stdout); err != nil {
+		_, _ = fmt.Fprintf(stderr, "%s htpasswd: %s\n", AppName, err)
+		return 1
+	}
+
+	return 0
+}
+
+// htpasswdCommand performs the requested htpasswd action.
+func htpasswdCommand(path, user, password string, create, del, verify, batch bool, stdin io.Reader, stdout io.Writer) error {
+	cfg := argon2.DefaultConfig()
+
+	var (
+		f   *htpasswd.File
+		err error
+	)
+	if create {
+		f = htpasswd.New(path, cfg)
+	} else if f, err = htpasswd.Open(path, cfg); err != nil {
+		return err
+	}
+
+	if del {
+		if err := f.Delete(user); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(stdout, "Deleting password for user %s\n", user)
+		return f.Save()
+	}
+
+	if !batch {
+		if password, err = readPassword(stdin); err != nil {
+			return err
+		}
+	}
+
+	if verify {
+		ok, err := f.Verify(user, []byte(password))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("password verification failed")
+		}
+		_, _ = fmt.Fprintf(stdout, "Password for user %s correct.\n", user)
+		return nil
+	}
+
+	action := "Adding"
+	if _, exists := f.Lookup(user); exists {
+		action = "Updating"
+	}
+	if err := f.Set(user, []byte(password)); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(stdout, "%s password for user %s\n", action, user)
+
+	return f.Save()
+}
+
+// readPassword reads the password from the first line of `r`.
+func readPassword(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("please provide a password")
+	}
+
+	return password, nil
+}
@@ -4,8 +4,9 @@ import (
 	"errors"
 	"flag"
 	"fmt"
-	"math"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/matthewhartstonge/argon2"
 )
@@ -23,20 +24,51 @@ var (
 
 var (
 	// configure flags
-	t       = flag.Uint("t", 0, "time cost specifies the number of iterations of argon2.")
-	m       = flag.Uint("m", 0, "memory cost specifies the amount of memory to use in kibibytes.")
-	p       = flag.Uint("p", 0, "parallelism cost specifies the number of parallel threads to spawn.")
-	hashLen = flag.Uint("hash-len", 0, "hash length specifies the length of the resulting hash in bytes.")
-	saltLen = flag.Uint("salt-len", 0, "salt length specifies the length of the resulting salt in bytes.")
 	s       = flag.Bool("s", false, "silent removes all cli output.")
+	profile = flag.String("profile", "", "profile selects the named parameters to start from, one of: "+strings.Join(argon2.Profiles(), ", ")+".")
+
+	// params collects the argon2 parameters set by flags, in order.
+	params []string
 )
 
+func init() {
+	paramFlag("t", "t", "time cost specifies the number of iterations of argon2.")
+	paramFlag("m", "m", "memory cost specifies the amount of memory to use in kibibytes, or using a KiB, MiB or GiB unit.")
+	paramFlag("p", "p", "parallelism cost specifies the number of parallel threads to spawn.")
+	paramFlag("threads", "threads", "threads limits the number of threads computing the lanes, without affecting the hash.")
+	paramFlag("hash-len", "hash-len", "hash length specifies the length of the resulting hash in bytes.")
+	paramFlag("salt-len", "salt-len", "salt length specifies the length of the resulting salt in bytes.")
+	flag.Func("config", `config sets parameters in the form "argon2id,m=64MiB,t=3,p=4".`, func(v string) error {
+		params = append(params, v)
+		return nil
+	})
+}
+
+// paramFlag defines a flag setting the argon2 parameter `key`.
+func paramFlag(name, key, usage string) {
+	flag.Func(name, usage, func(v string) error {
+		params = append(params, key+"="+v)
+		return nil
+	})
+}
+
 type config struct {
 	silent bool
 	argon  argon2.Config
 }
 
 func main() {
+	if filepath.Base(os.Args[0]) == CompatName {
+		os.Exit(runCompat(os.Args[0], os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "-compat" {
+		os.Exit(runCompat(os.Args[0]+" -compat", os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "htpasswd" {
+		os.Exit(runHtpasswd(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
 	setupFlagUsage()
 
 	cfg, err := parseFlags()
@@ -64,7 +96,9 @@ func main() {
 func setupFlagUsage() {
 	flag.Usage = func() {
 		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "NAME:\n\t%s - An Argon2id CLI hash generator\n\n", AppName)
-		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "USAGE:\n\t%s [command options] p@ssw0rd\n\n", AppName)
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "USAGE:\n\t%s [command options] p@ssw0rd\n", AppName)
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "\t%s htpasswd [-c] [-D|-v] [-b] passwordfile username [password]\n", AppName)
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "\t%s -compat salt [-i|-d|-id] [-t iterations] [-m log2(memory in KiB) | -k memory in KiB] [-p parallelism] [-l hash length] [-e|-r] [-v (10|13)]\n\n", AppName)
 		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "VERSION:\n\tv%s (%s) %s\n\n", AppVersion, AppCommit, AppCommitDate)
 		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "AUTHOR:\n\tMatthew Hartstonge - https://github.com/matthewhartstonge\n\n")
 		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "OPTIONS:\n")
@@ -82,44 +116,22 @@ func parseFlags() (*config, error) {
 	}
 
 	argon := argon2.RecommendedDefaults()
-	if *hashLen != 0 {
-		v, err := isUint32(*hashLen)
-		if err != nil {
-			return cfg, err
-		}
-		argon.HashLength = v
-	}
-
-	if *saltLen != 0 {
-		v, err := isUint32(*saltLen)
-		if err != nil {
-			return cfg, err
-		}
-		argon.SaltLength = v
-	}
-
-	if *t != 0 {
-		v, err := isUint32(*t)
+	if *profile != "" {
+		v, err := argon2.ProfileByName(*profile)
 		if err != nil {
 			return cfg, err
 		}
-		argon.TimeCost = v
+		argon = v
 	}
 
-	if *m != 0 {
-		v, err := isUint32(*m)
-		if err != nil {
-			return cfg, err
-		}
-		argon.MemoryCost = v
+	if err := argon.LoadEnv("ARGON2"); err != nil {
+		return cfg, err
 	}
 
-	if *p != 0 {
-		v, err := isUint8(*p)
-		if err != nil {
+	for _, param := range params {
+		if err := argon.Set(param); err != nil {
 			return cfg, err
 		}
-		argon.Parallelism = v
 	}
 
 	// inject argon config
@@ -141,13 +153,21 @@ func parsePassword() (string, error) {
 // hash encodes and returns a stringified argon2 hash.
 func hash(cfg *config, password string) (string, error) {
 	cliPrintf(cfg,
-		"Generating argon2id hash with m=%d, t=%d, p=%d...\n\n",
+		"Generating %s hash with m=%d, t=%d, p=%d...\n\n",
+		strings.ToLower(cfg.argon.Mode.String()),
 		cfg.argon.MemoryCost,
 		cfg.argon.TimeCost,
 		cfg.argon.Parallelism,
 	)
 
-	enc, err := cfg.argon.HashEncoded([]byte(password))
+	argon := cfg.argon
+	if !cfg.silent && isTerminal(os.Stdout) {
+		bar := newProgressBar(os.Stdout)
+		defer bar.clear()
+		argon.Options = &argon2.Options{Progress: bar.update}
+	}
+
+	enc, err := argon.HashEncoded([]byte(password))
 	if err != nil {
 		return "", err
 	}
@@ -155,24 +175,6 @@ func hash(cfg *config, password string) (string, error) {
 	return string(enc), nil
 }
 
-// isUint8 performs bounds checking for uint8
-func isUint8(i uint) (uint8, error) {
-	if i > math.MaxUint8 {
-		return 0, fmt.Errorf("argon2: invalid uint8 value: %d", i)
-	}
-
-	return uint8(i), nil
-}
-
-// isUint32 performs bounds checking for uint32
-func isUint32(i uint) (uint32, error) {
-	if i > math.MaxUint32 {
-		return 0, fmt.Errorf("argon2: invalid uint32 value: %d", i)
-	}
-
-	return uint32(i), nil
-}
-
 // cliPrintf provides a fmt.Printf wrapper that doesn't print if silence is
 // desired.
 func cliPrintf(cfg *config, format string, a ...any) {
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/matthewhartstonge/argon2"
+)
+
+// progressBarWidth is the number of characters between the progress bar's
+// brackets.
+const progressBarWidth = 40
+
+// progressBar renders hashing progress on a single, repeatedly overwritten,
+// terminal line.
+type progressBar struct {
+	w       io.Writer
+	percent int
+}
+
+// newProgressBar returns a progressBar writing to w.
+func newProgressBar(w io.Writer) *progressBar {
+	return &progressBar{w: w, percent: -1}
+}
+
+// update redraws the bar, if the percentage completed has changed. It
+// implements argon2.Options.Progress.
+func (b *progressBar) update(p argon2.Progress) {
+	percent := int(uint64(p.Completed) * 100 / uint64(p.Total))
+	if percent == b.percent {
+		return
+	}
+	b.percent = percent
+
+	done := percent * progressBarWidth / 100
+	_, _ = fmt.Fprintf(b.w, "\r[%s%s] %3d%%",
+		strings.Repeat("#", done),
+		strings.Repeat("-", progressBarWidth-done),
+		percent,
+	)
+}
+
+// clear erases the bar, if it has been drawn.
+func (b *progressBar) clear() {
+	if b.percent >= 0 {
+		_, _ = fmt.Fprint(b.w, "\r\033[K")
+	}
+}
+
+// isTerminal reports whether f is connected to a terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,135 @@
+//go:build cgo
+
+package main
+
+import (
+	"unsafe"
+
+	"github.com/matthewhartstonge/argon2"
+)
+
+// Input restrictions of libargon2, which are also declared in argon2.h.
+const (
+	minOutlen     = 4
+	minSaltLength = 8
+	minMemory     = 2 * 4 // 2 * ARGON2_SYNC_POINTS
+	// maxLanes and maxThreads are limited by argon2.Config, rather than
+	// 2^24-1 as in libargon2.
+	maxLanes   = 255
+	maxThreads = 255
+)
+
+// Flags of argon2_context.
+const (
+	flagClearPassword = 1 << 0
+	flagClearSecret   = 1 << 1
+)
+
+// context mirrors argon2_context using Go types. The pointers reference C
+// memory.
+type context struct {
+	out       unsafe.Pointer
+	outlen    uint32
+	pwd       unsafe.Pointer
+	pwdlen    uint32
+	salt      unsafe.Pointer
+	saltlen   uint32
+	secret    unsafe.Pointer
+	secretlen uint32
+	ad        unsafe.Pointer
+	adlen     uint32
+	tCost     uint32
+	mCost     uint32
+	lanes     uint32
+	threads   uint32
+	version   uint32
+
+	allocateCbk unsafe.Pointer
+	freeCbk     unsafe.Pointer
+}
+
+// validate checks `c` in the same order as libargon2's validate_inputs, so
+// the same error is returned for the same invalid input.
+func (c *context) validate() error {
+	switch {
+	case c.out == nil:
+		return argon2.ErrOutputPtrNull
+	case c.outlen < minOutlen:
+		return argon2.ErrOutputTooShort
+	case c.pwd == nil && c.pwdlen != 0:
+		return argon2.ErrPwdPtrMismatch
+	case c.salt == nil && c.saltlen != 0:
+		return argon2.ErrSaltPtrMismatch
+	case c.saltlen < minSaltLength:
+		return argon2.ErrSaltTooShort
+	case c.secret == nil && c.secretlen != 0:
+		return argon2.ErrSecretPtrMismatch
+	case c.ad == nil && c.adlen != 0:
+		return argon2.ErrAdPtrMismatch
+	case c.mCost < minMemory, uint64(c.mCost) < 8*uint64(c.lanes):
+		return argon2.ErrMemoryTooLittle
+	case c.tCost < 1:
+		return argon2.ErrTimeTooSmall
+	case c.lanes < 1:
+		return argon2.ErrLanesTooFew
+	case c.lanes > maxLanes:
+		return argon2.ErrLanesTooMany
+	case c.threads < 1:
+		return argon2.ErrThreadsTooFew
+	case c.threads > maxThreads:
+		return argon2.ErrThreadsTooMany
+	case c.allocateCbk != nil && c.freeCbk == nil:
+		return argon2.ErrFreeMemoryCbkNull
+	case c.allocateCbk == nil && c.freeCbk != nil:
+		return argon2.ErrAllocateMemoryCbkNull
+	}
+	return nil
+}
+
+// hash validates `c` and returns the hash of the password, without writing
+// it to c.out.
+func (c *context) hash(typ int) (argon2.Raw, error) {
+	if err := c.validate(); err != nil {
+		return argon2.Raw{}, err
+	}
+
+	mode, ok := modeOf(typ)
+	if !ok {
+		return argon2.Raw{}, argon2.ErrIncorrectType
+	}
+
+	cfg := argon2.Config{
+		HashLength:  c.outlen,
+		SaltLength:  c.saltlen,
+		TimeCost:    c.tCost,
+		MemoryCost:  c.mCost,
+		Parallelism: uint8(c.lanes),
+		Threads:     uint8(min(c.threads, c.lanes)),
+		Mode:        mode,
+		Version:     argon2.Version(c.version),
+	}
+	if c.secretlen > 0 || c.adlen > 0 || cfg.Version == argon2.Version10 {
+		cfg.Options = &argon2.Options{
+			Secret:          bytesAt(c.secret, c.secretlen),
+			AssociatedData:  bytesAt(c.ad, c.adlen),
+			StrictVersion10: true,
+		}
+	}
+
+	return cfg.Hash(bytesAt(c.pwd, c.pwdlen), bytesAt(c.salt, c.saltlen))
+}
+
+// modeOf returns the mode of an argon2_type.
+func modeOf(typ int) (argon2.Mode, bool) {
+	mode := argon2.Mode(typ)
+	return mode, typ >= 0 && mode.String() != "unknown"
+}
+
+// bytesAt returns the n bytes of C memory at p. The slice is never nil, as
+// argon2.Config.Hash treats nil passwords and salts specially.
+func bytesAt(p unsafe.Pointer, n uint32) []byte {
+	if n == 0 {
+		return []byte{}
+	}
+	return unsafe.Slice((*byte)(p), n)
+}
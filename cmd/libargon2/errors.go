@@ -0,0 +1,47 @@
+//go:build cgo
+
+package main
+
+// errorMessages are the messages returned by argon2_error_message, indexed
+// by the negated error code.
+var errorMessages = []string{
+	"OK",
+	"Output pointer is NULL",
+	"Output is too short",
+	"Output is too long",
+	"Password is too short",
+	"Password is too long",
+	"Salt is too short",
+	"Salt is too long",
+	"Associated data is too short",
+	"Associated data is too long",
+	"Secret is too short",
+	"Secret is too long",
+	"Time cost is too small",
+	"Time cost is too large",
+	"Memory cost is too small",
+	"Memory cost is too large",
+	"Too few lanes",
+	"Too many lanes",
+	"Password pointer is NULL, but password length is not 0",
+	"Salt pointer is NULL, but salt length is not 0",
+	"Secret pointer is NULL, but secret length is not 0",
+	"Associated data pointer is NULL, but ad length is not 0",
+	"Memory allocation error",
+	"The free memory callback is NULL",
+	"The allocate memory callback is NULL",
+	"Argon2_Context context is NULL",
+	"There is no such version of Argon2",
+	"Output pointer mismatch",
+	"Not enough threads",
+	"Too many threads",
+	"Missing arguments",
+	"Encoding failed",
+	"Decoding failed",
+	"Threading failure",
+	"Some of encoded parameters are too long or too short",
+	"The password does not match the supplied hash",
+}
+
+// unknownErrorMessage is returned by argon2_error_message for unknown codes.
+const unknownErrorMessage = "Unknown error code"
@@ -0,0 +1,371 @@
+// Command libargon2 builds a C shared library exposing the libargon2 API, as
+// declared by argon2.h, so C/C++ programs and FFI consumers can link this
+// implementation in place of the reference one:
+//
+//	go build -buildmode=c-shared -o libargon2.so ./cmd/libargon2
+//
+// Functions return the numeric libargon2 error codes.
+package main
+
+/*
+#define ARGON2_NO_PROTOTYPES
+#include <string.h>
+#include "argon2.h"
+*/
+import "C"
+
+import (
+	"crypto/subtle"
+	"errors"
+	"math"
+	"unsafe"
+
+	"github.com/matthewhartstonge/argon2"
+)
+
+// cErrorMessages and cTypeNames are allocated once, as the returned strings
+// must remain valid for the lifetime of the library.
+var (
+	cErrorMessages []*C.char
+	cUnknownError  *C.char
+	cTypeNames     [2][]*C.char
+	cTypeNameModes = []argon2.Mode{argon2.ModeArgon2d, argon2.ModeArgon2i, argon2.ModeArgon2id}
+)
+
+func init() {
+	for _, msg := range errorMessages {
+		cErrorMessages = append(cErrorMessages, C.CString(msg))
+	}
+	cUnknownError = C.CString(unknownErrorMessage)
+
+	for _, mode := range cTypeNameModes {
+		name := mode.String()
+		cTypeNames[0] = append(cTypeNames[0], C.CString("a"+name[1:]))
+		cTypeNames[1] = append(cTypeNames[1], C.CString(name))
+	}
+}
+
+// main is required to build a c-shared library, but never called.
+func main() {}
+
+// errorCode returns the libargon2 error code of `err`.
+func errorCode(err error) C.int {
+	if err == nil {
+		return C.ARGON2_OK
+	}
+
+	var coder interface{ Code() int }
+	if errors.As(err, &coder) {
+		return C.int(coder.Code())
+	}
+
+	// Config.Hash only fails on invalid input, which is an argon2.Error.
+	return C.ARGON2_INCORRECT_PARAMETER
+}
+
+// goContext copies an argon2_context.
+func goContext(ctx *C.argon2_context) *context {
+	return &context{
+		out:         unsafe.Pointer(ctx.out),
+		outlen:      uint32(ctx.outlen),
+		pwd:         unsafe.Pointer(ctx.pwd),
+		pwdlen:      uint32(ctx.pwdlen),
+		salt:        unsafe.Pointer(ctx.salt),
+		saltlen:     uint32(ctx.saltlen),
+		secret:      unsafe.Pointer(ctx.secret),
+		secretlen:   uint32(ctx.secretlen),
+		ad:          unsafe.Pointer(ctx.ad),
+		adlen:       uint32(ctx.adlen),
+		tCost:       uint32(ctx.t_cost),
+		mCost:       uint32(ctx.m_cost),
+		lanes:       uint32(ctx.lanes),
+		threads:     uint32(ctx.threads),
+		version:     uint32(ctx.version),
+		allocateCbk: unsafe.Pointer(ctx.allocate_cbk),
+		freeCbk:     unsafe.Pointer(ctx.free_cbk),
+	}
+}
+
+//export argon2_type2string
+func argon2_type2string(typ C.argon2_type, uppercase C.int) *C.char {
+	if _, ok := modeOf(int(typ)); !ok {
+		return nil
+	}
+	if uppercase != 0 {
+		return cTypeNames[1][typ]
+	}
+	return cTypeNames[0][typ]
+}
+
+//export argon2_ctx
+func argon2_ctx(ctx *C.argon2_context, typ C.argon2_type) C.int {
+	if ctx == nil {
+		return C.ARGON2_INCORRECT_PARAMETER
+	}
+
+	c := goContext(ctx)
+	raw, err := c.hash(int(typ))
+	if err != nil {
+		return errorCode(err)
+	}
+	copy(bytesAt(c.out, c.outlen), raw.Hash)
+
+	if ctx.flags&flagClearPassword != 0 {
+		argon2.SecureZeroMemory(bytesAt(c.pwd, c.pwdlen))
+		ctx.pwdlen = 0
+	}
+	if ctx.flags&flagClearSecret != 0 {
+		argon2.SecureZeroMemory(bytesAt(c.secret, c.secretlen))
+		ctx.secretlen = 0
+	}
+
+	return C.ARGON2_OK
+}
+
+//export argon2_hash
+func argon2_hash(tCost, mCost, parallelism C.uint32_t, pwd unsafe.Pointer, pwdlen C.size_t,
+	salt unsafe.Pointer, saltlen C.size_t, hash unsafe.Pointer, hashlen C.size_t,
+	encoded *C.char, encodedlen C.size_t, typ C.argon2_type, version C.uint32_t,
+) C.int {
+	switch {
+	case uint64(pwdlen) > math.MaxUint32:
+		return C.ARGON2_PWD_TOO_LONG
+	case uint64(saltlen) > math.MaxUint32:
+		return C.ARGON2_SALT_TOO_LONG
+	case uint64(hashlen) > math.MaxUint32:
+		return C.ARGON2_OUTPUT_TOO_LONG
+	case hashlen < minOutlen:
+		return C.ARGON2_OUTPUT_TOO_SHORT
+	}
+
+	// The output is written to a Go slice, so out only needs to be set to
+	// pass validation.
+	c := &context{
+		out:     unsafe.Pointer(&struct{}{}),
+		outlen:  uint32(hashlen),
+		pwd:     pwd,
+		pwdlen:  uint32(pwdlen),
+		salt:    salt,
+		saltlen: uint32(saltlen),
+		tCost:   uint32(tCost),
+		mCost:   uint32(mCost),
+		lanes:   uint32(parallelism),
+		threads: uint32(parallelism),
+		version: uint32(version),
+	}
+	raw, err := c.hash(int(typ))
+	if err != nil {
+		return errorCode(err)
+	}
+
+	defer argon2.SecureZeroMemory(raw.Hash)
+
+	// The encoded hash is written first, so that the caller's hash buffer is
+	// left untouched if it doesn't fit.
+	if encoded != nil && encodedlen > 0 {
+		enc := raw.Encode()
+		dst := unsafe.Slice((*byte)(unsafe.Pointer(encoded)), encodedlen)
+		if len(enc) >= len(dst) {
+			argon2.SecureZeroMemory(dst)
+			return C.ARGON2_ENCODING_FAIL
+		}
+		dst[copy(dst, enc)] = 0
+	}
+
+	if hash != nil {
+		copy(bytesAt(hash, uint32(hashlen)), raw.Hash)
+	}
+
+	return C.ARGON2_OK
+}
+
+//export argon2i_hash_encoded
+func argon2i_hash_encoded(tCost, mCost, parallelism C.uint32_t, pwd unsafe.Pointer, pwdlen C.size_t,
+	salt unsafe.Pointer, saltlen C.size_t, hashlen C.size_t, encoded *C.char, encodedlen C.size_t,
+) C.int {
+	return argon2_hash(tCost, mCost, parallelism, pwd, pwdlen, salt, saltlen, nil, hashlen,
+		encoded, encodedlen, C.Argon2_i, C.ARGON2_VERSION_NUMBER)
+}
+
+//export argon2i_hash_raw
+func argon2i_hash_raw(tCost, mCost, parallelism C.uint32_t, pwd unsafe.Pointer, pwdlen C.size_t,
+	salt unsafe.Pointer, saltlen C.size_t, hash unsafe.Pointer, hashlen C.size_t,
+) C.int {
+	return argon2_hash(tCost, mCost, parallelism, pwd, pwdlen, salt, saltlen, hash, hashlen,
+		nil, 0, C.Argon2_i, C.ARGON2_VERSION_NUMBER)
+}
+
+//export argon2d_hash_encoded
+func argon2d_hash_encoded(tCost, mCost, parallelism C.uint32_t, pwd unsafe.Pointer, pwdlen C.size_t,
+	salt unsafe.Pointer, saltlen C.size_t, hashlen C.size_t, encoded *C.char, encodedlen C.size_t,
+) C.int {
+	return argon2_hash(tCost, mCost, parallelism, pwd, pwdlen, salt, saltlen, nil, hashlen,
+		encoded, encodedlen, C.Argon2_d, C.ARGON2_VERSION_NUMBER)
+}
+
+//export argon2d_hash_raw
+func argon2d_hash_raw(tCost, mCost, parallelism C.uint32_t, pwd unsafe.Pointer, pwdlen C.size_t,
+	salt unsafe.Pointer, saltlen C.size_t, hash unsafe.Pointer, hashlen C.size_t,
+) C.int {
+	return argon2_hash(tCost, mCost, parallelism, pwd, pwdlen, salt, saltlen, hash, hashlen,
+		nil, 0, C.Argon2_d, C.ARGON2_VERSION_NUMBER)
+}
+
+//export argon2id_hash_encoded
+func argon2id_hash_encoded(tCost, mCost, parallelism C.uint32_t, pwd unsafe.Pointer, pwdlen C.size_t,
+	salt unsafe.Pointer, saltlen C.size_t, hashlen C.size_t, encoded *C.char, encodedlen C.size_t,
+) C.int {
+	return argon2_hash(tCost, mCost, parallelism, pwd, pwdlen, salt, saltlen, nil, hashlen,
+		encoded, encodedlen, C.Argon2_id, C.ARGON2_VERSION_NUMBER)
+}
+
+//export argon2id_hash_raw
+func argon2id_hash_raw(tCost, mCost, parallelism C.uint32_t, pwd unsafe.Pointer, pwdlen C.size_t,
+	salt unsafe.Pointer, saltlen C.size_t, hash unsafe.Pointer, hashlen C.size_t,
+) C.int {
+	return argon2_hash(tCost, mCost, parallelism, pwd, pwdlen, salt, saltlen, hash, hashlen,
+		nil, 0, C.Argon2_id, C.ARGON2_VERSION_NUMBER)
+}
+
+//export argon2_verify
+func argon2_verify(encoded *C.char, pwd unsafe.Pointer, pwdlen C.size_t, typ C.argon2_type) C.int {
+	switch {
+	case uint64(pwdlen) > math.MaxUint32:
+		return C.ARGON2_PWD_TOO_LONG
+	case encoded == nil:
+		return C.ARGON2_DECODING_FAIL
+	}
+
+	mode, ok := modeOf(int(typ))
+	if !ok {
+		return C.ARGON2_INCORRECT_TYPE
+	}
+
+	// As in libargon2, hashes of a different type, or with parameters it
+	// doesn't know, such as wrapped hashes, fail to decode.
+	raw, err := argon2.Decode([]byte(C.GoString(encoded)))
+	if err != nil || raw.Config.Mode != mode || raw.Wrap != "" {
+		return C.ARGON2_DECODING_FAIL
+	}
+
+	c := &context{
+		out:     unsafe.Pointer(&struct{}{}),
+		outlen:  raw.Config.HashLength,
+		pwd:     pwd,
+		pwdlen:  uint32(pwdlen),
+		salt:    unsafe.Pointer(unsafe.SliceData(raw.Salt)),
+		saltlen: raw.Config.SaltLength,
+		tCost:   raw.Config.TimeCost,
+		mCost:   raw.Config.MemoryCost,
+		lanes:   uint32(raw.Config.Parallelism),
+		threads: uint32(raw.Config.Parallelism),
+		version: uint32(raw.Config.Version),
+	}
+	r, err := c.hash(int(typ))
+	if err != nil {
+		return errorCode(err)
+	}
+
+	if subtle.ConstantTimeCompare(r.Hash, raw.Hash) != 1 {
+		return C.ARGON2_VERIFY_MISMATCH
+	}
+	return C.ARGON2_OK
+}
+
+//export argon2i_verify
+func argon2i_verify(encoded *C.char, pwd unsafe.Pointer, pwdlen C.size_t) C.int {
+	return argon2_verify(encoded, pwd, pwdlen, C.Argon2_i)
+}
+
+//export argon2d_verify
+func argon2d_verify(encoded *C.char, pwd unsafe.Pointer, pwdlen C.size_t) C.int {
+	return argon2_verify(encoded, pwd, pwdlen, C.Argon2_d)
+}
+
+//export argon2id_verify
+func argon2id_verify(encoded *C.char, pwd unsafe.Pointer, pwdlen C.size_t) C.int {
+	return argon2_verify(encoded, pwd, pwdlen, C.Argon2_id)
+}
+
+//export argon2d_ctx
+func argon2d_ctx(ctx *C.argon2_context) C.int {
+	return argon2_ctx(ctx, C.Argon2_d)
+}
+
+//export argon2i_ctx
+func argon2i_ctx(ctx *C.argon2_context) C.int {
+	return argon2_ctx(ctx, C.Argon2_i)
+}
+
+//export argon2id_ctx
+func argon2id_ctx(ctx *C.argon2_context) C.int {
+	return argon2_ctx(ctx, C.Argon2_id)
+}
+
+//export argon2_verify_ctx
+func argon2_verify_ctx(ctx *C.argon2_context, hash *C.char, typ C.argon2_type) C.int {
+	if ret := argon2_ctx(ctx, typ); ret != C.ARGON2_OK {
+		return ret
+	}
+
+	out := bytesAt(unsafe.Pointer(ctx.out), uint32(ctx.outlen))
+	want := bytesAt(unsafe.Pointer(hash), uint32(ctx.outlen))
+	if subtle.ConstantTimeCompare(out, want) != 1 {
+		return C.ARGON2_VERIFY_MISMATCH
+	}
+	return C.ARGON2_OK
+}
+
+//export argon2d_verify_ctx
+func argon2d_verify_ctx(ctx *C.argon2_context, hash *C.char) C.int {
+	return argon2_verify_ctx(ctx, hash, C.Argon2_d)
+}
+
+//export argon2i_verify_ctx
+func argon2i_verify_ctx(ctx *C.argon2_context, hash *C.char) C.int {
+	return argon2_verify_ctx(ctx, hash, C.Argon2_i)
+}
+
+//export argon2id_verify_ctx
+func argon2id_verify_ctx(ctx *C.argon2_context, hash *C.char) C.int {
+	return argon2_verify_ctx(ctx, hash, C.Argon2_id)
+}
+
+//export argon2_error_message
+func argon2_error_message(code C.int) *C.char {
+	if code > 0 || int(-code) >= len(cErrorMessages) {
+		return cUnknownError
+	}
+	return cErrorMessages[-code]
+}
+
+//export argon2_encodedlen
+func argon2_encodedlen(tCost, mCost, parallelism, saltlen, hashlen C.uint32_t, typ C.argon2_type) C.size_t {
+	name := argon2_type2string(typ, 0)
+	if name == nil {
+		return 0
+	}
+
+	n := len("$$v=$m=,t=,p=$$") + int(C.strlen(name)) +
+		numLen(uint32(tCost)) + numLen(uint32(mCost)) + numLen(uint32(parallelism)) +
+		b64Len(uint32(saltlen)) + b64Len(uint32(hashlen)) + numLen(argon2.Version13) + 1
+	return C.size_t(n)
+}
+
+// numLen returns the number of decimal digits of n.
+func numLen(n uint32) int {
+	l := 1
+	for ; n >= 10; n /= 10 {
+		l++
+	}
+	return l
+}
+
+// b64Len returns the length of n bytes encoded using unpadded base64.
+func b64Len(n uint32) int {
+	l := int(n/3) * 4
+	if rem := n % 3; rem != 0 {
+		l += int(rem) + 1
+	}
+	return l
+}
@@ -0,0 +1,214 @@
+/*
+ * Tests libargon2.so against vectors generated by the reference libargon2,
+ * after the reference implementation's src/test.c.
+ */
+
+#include <assert.h>
+#include <stdint.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+
+#include "argon2.h"
+
+#define OUT_LEN 32
+#define ENCODED_LEN 108
+
+static void hashtest(uint32_t version, uint32_t t, uint32_t m, uint32_t p,
+                     char *pwd, char *salt, argon2_type type, char *hexref,
+                     char *mcfref) {
+    unsigned char out[OUT_LEN];
+    unsigned char hex_out[OUT_LEN * 2 + 1];
+    char encoded[ENCODED_LEN];
+    int ret, i;
+
+    printf("Hash test: $v=%d t=%d, m=%d, p=%d, pass=%s, salt=%s: ", version,
+           t, m, p, pwd, salt);
+
+    ret = argon2_hash(t, 1 << m, p, pwd, strlen(pwd), salt, strlen(salt), out,
+                      OUT_LEN, encoded, ENCODED_LEN, type, version);
+    assert(ret == ARGON2_OK);
+
+    for (i = 0; i < OUT_LEN; ++i)
+        sprintf((char *)(hex_out + i * 2), "%02x", out[i]);
+
+    assert(memcmp(hex_out, hexref, OUT_LEN * 2) == 0);
+    assert(strcmp(encoded, mcfref) == 0);
+    assert(argon2_encodedlen(t, 1 << m, p, strlen(salt), OUT_LEN, type) ==
+           strlen(mcfref) + 1);
+
+    ret = argon2_verify(encoded, pwd, strlen(pwd), type);
+    assert(ret == ARGON2_OK);
+    ret = argon2_verify(mcfref, pwd, strlen(pwd), type);
+    assert(ret == ARGON2_OK);
+
+    printf("PASS\n");
+}
+
+static void ctxtest(argon2_type type, char *hexref) {
+    unsigned char pwd[32], salt[16], secret[8], ad[12];
+    unsigned char out[OUT_LEN];
+    unsigned char hex_out[OUT_LEN * 2 + 1];
+    argon2_context ctx;
+    int ret, i;
+
+    printf("Context test: %s with secret and associated data: ",
+           argon2_type2string(type, 1));
+
+    memset(pwd, 0x01, sizeof(pwd));
+    memset(salt, 0x02, sizeof(salt));
+    memset(secret, 0x03, sizeof(secret));
+    memset(ad, 0x04, sizeof(ad));
+
+    memset(&ctx, 0, sizeof(ctx));
+    ctx.out = out;
+    ctx.outlen = OUT_LEN;
+    ctx.pwd = pwd;
+    ctx.pwdlen = sizeof(pwd);
+    ctx.salt = salt;
+    ctx.saltlen = sizeof(salt);
+    ctx.secret = secret;
+    ctx.secretlen = sizeof(secret);
+    ctx.ad = ad;
+    ctx.adlen = sizeof(ad);
+    ctx.t_cost = 3;
+    ctx.m_cost = 32;
+    ctx.lanes = 4;
+    ctx.threads = 4;
+    ctx.version = ARGON2_VERSION_13;
+
+    ret = argon2_ctx(&ctx, type);
+    assert(ret == ARGON2_OK);
+
+    for (i = 0; i < OUT_LEN; ++i)
+        sprintf((char *)(hex_out + i * 2), "%02x", out[i]);
+    assert(memcmp(hex_out, hexref, OUT_LEN * 2) == 0);
+
+    ret = argon2_verify_ctx(&ctx, (char *)out, type);
+    assert(ret == ARGON2_OK);
+
+    ctx.flags = ARGON2_FLAG_CLEAR_PASSWORD | ARGON2_FLAG_CLEAR_SECRET;
+    ret = argon2_ctx(&ctx, type);
+    assert(ret == ARGON2_OK);
+    assert(ctx.pwdlen == 0 && pwd[0] == 0);
+    assert(ctx.secretlen == 0 && secret[0] == 0);
+
+    printf("PASS\n");
+}
+
+int main(void) {
+    int i, ret;
+    unsigned char out[OUT_LEN];
+    char encoded[ENCODED_LEN];
+    const char *msg;
+
+    hashtest(0x13, 2, 16, 1, "password", "somesalt", Argon2_i,
+             "c1628832147d9720c5bd1cfd61367078729f6dfb6f8fea9ff98158e0d7816ed0",
+             "$argon2i$v=19$m=65536,t=2,p=1$c29tZXNhbHQ"
+             "$wWKIMhR9lyDFvRz9YTZweHKfbftvj+qf+YFY4NeBbtA");
+    hashtest(0x13, 2, 16, 1, "password", "somesalt", Argon2_d,
+             "955e5d5b163a1b60bba35fc36d0496474fba4f6b59ad53628666f07fb2f93eaf",
+             "$argon2d$v=19$m=65536,t=2,p=1$c29tZXNhbHQ"
+             "$lV5dWxY6G2C7o1/DbQSWR0+6T2tZrVNihmbwf7L5Pq8");
+    hashtest(0x13, 2, 16, 1, "password", "somesalt", Argon2_id,
+             "09316115d5cf24ed5a15a31a3ba326e5cf32edc24702987c02b6566f61913cf7",
+             "$argon2id$v=19$m=65536,t=2,p=1$c29tZXNhbHQ"
+             "$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc");
+    hashtest(0x13, 2, 8, 2, "password", "somesalt", Argon2_i,
+             "4ff5ce2769a1d7f4c8a491df09d41a9fbe90e5eb02155a13e4c01e20cd4eab61",
+             "$argon2i$v=19$m=256,t=2,p=2$c29tZXNhbHQ"
+             "$T/XOJ2mh1/TIpJHfCdQan76Q5esCFVoT5MAeIM1Oq2E");
+    hashtest(0x13, 1, 16, 1, "password", "diffsalt", Argon2_id,
+             "9360b2af4c53956e987b75b0f2337a57877055c3f0d2a2c0079d0eca1f3aa5b8",
+             "$argon2id$v=19$m=65536,t=1,p=1$ZGlmZnNhbHQ"
+             "$k2Cyr0xTlW6Ye3Ww8jN6V4dwVcPw0qLAB50Oyh86pbg");
+    hashtest(0x13, 4, 10, 4, "differentpassword", "somesalt", Argon2_id,
+             "257349fddedb4e81cc5ed4759296b9659295c33705a802d31bfc82d2444dc0d8",
+             "$argon2id$v=19$m=1024,t=4,p=4$c29tZXNhbHQ"
+             "$JXNJ/d7bToHMXtR1kpa5ZZKVwzcFqALTG/yC0kRNwNg");
+    hashtest(0x10, 2, 16, 1, "password", "somesalt", Argon2_i,
+             "f6c4db4a54e2a370627aff3db6176b94a2a209a62c8e36152711802f7b30c694",
+             "$argon2i$v=16$m=65536,t=2,p=1$c29tZXNhbHQ"
+             "$9sTbSlTio3Biev89thdrlKKiCaYsjjYVJxGAL3swxpQ");
+    hashtest(0x10, 2, 16, 1, "password", "somesalt", Argon2_d,
+             "2ec0d925358f5830caf0c1cc8a3ee58b34505759428b859c79b72415f51f9221",
+             "$argon2d$v=16$m=65536,t=2,p=1$c29tZXNhbHQ"
+             "$LsDZJTWPWDDK8MHMij7lizRQV1lCi4WcebckFfUfkiE");
+    hashtest(0x10, 2, 16, 1, "password", "somesalt", Argon2_id,
+             "980ebd24a4e667f16346f9d4a78b175728783613e0cc6fb17c2ec884b16435df",
+             "$argon2id$v=16$m=65536,t=2,p=1$c29tZXNhbHQ"
+             "$mA69JKTmZ/FjRvnUp4sXVyh4NhPgzG+xfC7IhLFkNd8");
+
+    ctxtest(Argon2_d,
+            "512b391b6f1162975371d30919734294f868e3be3984f3c1a13a4db9fabe4acb");
+    ctxtest(Argon2_i,
+            "c814d9d1dc7f37aa13f0d77f2494bda1c8de6b016dd388d29952a4c4672b6ce8");
+    ctxtest(Argon2_id,
+            "0d640df58d78766c08c037a34a8b53c9d01ef0452d75b65eb52520e96b01e659");
+
+    /* Error states */
+    printf("Error tests: ");
+
+    ret = argon2_verify("$argon2i$v=19$m=65536,t=2,p=1$c29tZXNhbHQ"
+                        "$wWKIMhR9lyDFvRz9YTZweHKfbftvj+qf+YFY4NeBbtA",
+                        "wrongpassword", strlen("wrongpassword"), Argon2_i);
+    assert(ret == ARGON2_VERIFY_MISMATCH);
+    msg = argon2_error_message(ret);
+    assert(strcmp(msg, "The password does not match the supplied hash") == 0);
+
+    ret = argon2_verify("$argon2i$v=19$m=65536,t=2,p=1c29tZXNhbHQ"
+                        "$9sTbSlTio3Biev89thdrlKKiCaYsjjYVJxGAL3swxpQ",
+                        "password", strlen("password"), Argon2_i);
+    assert(ret == ARGON2_DECODING_FAIL);
+
+    ret = argon2_verify("$argon2id$v=19$m=65536,t=2,p=1$c29tZXNhbHQ"
+                        "$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc",
+                        "password", strlen("password"), Argon2_i);
+    assert(ret == ARGON2_DECODING_FAIL);
+
+    ret = argon2_verify(NULL, "password", strlen("password"), Argon2_i);
+    assert(ret == ARGON2_DECODING_FAIL);
+
+    ret = argon2_hash(2, 1 << 12, 1, "password", strlen("password"), "short",
+                      strlen("short"), out, OUT_LEN, NULL, 0, Argon2_id,
+                      ARGON2_VERSION_13);
+    assert(ret == ARGON2_SALT_TOO_SHORT);
+    msg = argon2_error_message(ret);
+    assert(strcmp(msg, "Salt is too short") == 0);
+
+    ret = argon2_hash(2, 1, 1, "password", strlen("password"), "diffsalt",
+                      strlen("diffsalt"), out, OUT_LEN, NULL, 0, Argon2_id,
+                      ARGON2_VERSION_13);
+    assert(ret == ARGON2_MEMORY_TOO_LITTLE);
+
+    ret = argon2_hash(2, 1 << 12, 1, "password", strlen("password"),
+                      "diffsalt", strlen("diffsalt"), out, 3, NULL, 0,
+                      Argon2_id, ARGON2_VERSION_13);
+    assert(ret == ARGON2_OUTPUT_TOO_SHORT);
+
+    memset(out, 0xaa, OUT_LEN);
+    ret = argon2_hash(2, 1 << 12, 1, "password", strlen("password"),
+                      "diffsalt", strlen("diffsalt"), out, OUT_LEN, encoded,
+                      16, Argon2_id, ARGON2_VERSION_13);
+    assert(ret == ARGON2_ENCODING_FAIL);
+    for (i = 0; i < OUT_LEN; i++) {
+        assert(out[i] == 0xaa);
+    }
+
+    ret = argon2_hash(2, 1 << 12, 1, "password", strlen("password"),
+                      "diffsalt", strlen("diffsalt"), out, OUT_LEN, NULL, 0,
+                      (argon2_type)3, ARGON2_VERSION_13);
+    assert(ret == ARGON2_INCORRECT_TYPE);
+
+    ret = argon2_ctx(NULL, Argon2_id);
+    assert(ret == ARGON2_INCORRECT_PARAMETER);
+
+    assert(strcmp(argon2_error_message(ARGON2_OK), "OK") == 0);
+    assert(strcmp(argon2_error_message(1), "Unknown error code") == 0);
+    assert(strcmp(argon2_type2string(Argon2_id, 0), "argon2id") == 0);
+    assert(argon2_type2string((argon2_type)3, 0) == NULL);
+
+    printf("PASS\n");
+
+    return 0;
+}
@@ -0,0 +1,260 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package argon2
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// memoryUnits maps the case-insensitive units accepted for memory costs to
+// their size in KiB.
+var memoryUnits = map[string]uint64{
+	"":    1,
+	"k":   1,
+	"kib": 1,
+	"m":   1 << 10,
+	"mib": 1 << 10,
+	"g":   1 << 20,
+	"gib": 1 << 20,
+	"t":   1 << 30,
+	"tib": 1 << 30,
+}
+
+// envParams maps the environment variables read by LoadEnv, without their
+// prefix, to the parameters accepted by ParseConfig.
+var envParams = []struct {
+	name, key string
+}{
+	{"VERSION", "v"},
+	{"MEMORY", "m"},
+	{"TIME", "t"},
+	{"PARALLELISM", "p"},
+	{"THREADS", "threads"},
+	{"HASH_LENGTH", "hash-len"},
+	{"SALT_LENGTH", "salt-len"},
+}
+
+// ParseConfig parses a comma separated list of parameters, such as
+// "argon2id,m=65536,t=3,p=4", as returned by Config.String.
+//
+// The first element may name a mode, as accepted by ParseMode, or a profile,
+// as accepted by ProfileByName, which the following "key=value" parameters
+// override:
+//   - v: the version, as accepted by ParseVersion.
+//   - m: the memory cost in KiB, or using a KiB, MiB, GiB or TiB unit, e.g.
+//     "64MiB".
+//   - t: the time cost.
+//   - p: the parallelism.
+//   - threads: the Threads limit.
+//   - hash-len: the hash length in bytes.
+//   - salt-len: the salt length in bytes.
+//
+// Parameters which aren't given are taken from DefaultConfig.
+func ParseConfig(s string) (Config, error) {
+	c := DefaultConfig()
+	if err := c.Set(s); err != nil {
+		return Config{}, err
+	}
+	return c, nil
+}
+
+// String returns the parameters of `c` in the form accepted by
+// ParseConfig. The version, Threads, HashLength and SaltLength are only
+// included if they differ from DefaultConfig, a zero version being
+// Version13. Options are omitted.
+func (c Config) String() string {
+	c = *c.withDefaults()
+	d := DefaultConfig()
+
+	b := []byte(strings.ToLower(c.Mode.String()))
+	if c.Version != d.Version {
+		b = append(b, ",v="...)
+		b = strconv.AppendUint(b, uint64(c.Version), 10)
+	}
+	b = append(b, ",m="...)
+	b = strconv.AppendUint(b, uint64(c.MemoryCost), 10)
+	b = append(b, ",t="...)
+	b = strconv.AppendUint(b, uint64(c.TimeCost), 10)
+	b = append(b, ",p="...)
+	b = strconv.AppendUint(b, uint64(c.Parallelism), 10)
+	if c.Threads != d.Threads {
+		b = append(b, ",threads="...)
+		b = strconv.AppendUint(b, uint64(c.Threads), 10)
+	}
+	if c.HashLength != d.HashLength {
+		b = append(b, ",hash-len="...)
+		b = strconv.AppendUint(b, uint64(c.HashLength), 10)
+	}
+	if c.SaltLength != d.SaltLength {
+		b = append(b, ",salt-len="...)
+		b = strconv.AppendUint(b, uint64(c.SaltLength), 10)
+	}
+
+	return string(b)
+}
+
+// Set implements flag.Value, overriding the parameters of `c` with those
+// given in `s`, in the form accepted by ParseConfig. `c` is left unchanged
+// if `s` is invalid.
+func (c *Config) Set(s string) error {
+	if s == "" {
+		return ErrMissingArgs
+	}
+
+	cfg := *c
+	for i, param := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(param, "=")
+		if ok {
+			if err := cfg.setParam(key, value); err != nil {
+				return fmt.Errorf("argon2: invalid parameter %q: %w", param, err)
+			}
+			continue
+		}
+
+		if i > 0 {
+			return fmt.Errorf("argon2: invalid parameter %q: %w", param, ErrDecodingFail)
+		}
+		if mode, err := ParseMode(param); err == nil {
+			cfg.Mode = mode
+			continue
+		}
+
+		p, err := ProfileByName(param)
+		if err != nil {
+			return err
+		}
+		p.Options = cfg.Options
+		cfg = p
+	}
+
+	if err := cfg.validate(nil, nil); err != nil {
+		return err
+	}
+	*c = cfg
+	return nil
+}
+
+// LoadEnv overrides the parameters of `c` with those set in the
+// environment, using variables named `prefix` followed by an underscore and
+// one of:
+//   - CONFIG: parameters in the form accepted by ParseConfig, applied
+//     before the other variables.
+//   - MODE: the mode, as accepted by ParseMode.
+//   - VERSION, MEMORY, TIME, PARALLELISM, THREADS, HASH_LENGTH and
+//     SALT_LENGTH: the values of ParseConfig's v, m, t, p, threads,
+//     hash-len and salt-len parameters, e.g. ARGON2_MEMORY=64MiB.
+//
+// `c` is left unchanged if any of the variables is invalid.
+func (c *Config) LoadEnv(prefix string) error {
+	cfg := *c
+	if s, ok := os.LookupEnv(prefix + "_CONFIG"); ok {
+		if err := cfg.Set(s); err != nil {
+			return fmt.Errorf("argon2: %s_CONFIG: %w", prefix, err)
+		}
+	}
+
+	if s, ok := os.LookupEnv(prefix + "_MODE"); ok {
+		mode, err := ParseMode(s)
+		if err != nil {
+			return fmt.Errorf("argon2: %s_MODE: %w", prefix, err)
+		}
+		cfg.Mode = mode
+	}
+
+	for _, p := range envParams {
+		s, ok := os.LookupEnv(prefix + "_" + p.name)
+		if !ok {
+			continue
+		}
+		if err := cfg.setParam(p.key, s); err != nil {
+			return fmt.Errorf("argon2: %s_%s: %w", prefix, p.name, err)
+		}
+	}
+
+	if err := cfg.validate(nil, nil); err != nil {
+		return err
+	}
+	*c = cfg
+	return nil
+}
+
+// setParam sets the parameter named `key` to `value`.
+func (c *Config) setParam(key, value string) error {
+	var err error
+	switch key {
+	case "v":
+		c.Version, err = ParseVersion(value)
+	case "m":
+		c.MemoryCost, err = parseMemory(value)
+	case "t":
+		c.TimeCost, err = parseUint[uint32](value, ErrTimeTooLarge)
+	case "p":
+		c.Parallelism, err = parseUint[uint8](value, ErrLanesTooMany)
+	case "threads":
+		c.Threads, err = parseUint[uint8](value, ErrThreadsTooMany)
+	case "hash-len":
+		c.HashLength, err = parseUint[uint32](value, ErrOutputTooLong)
+	case "salt-len":
+		c.SaltLength, err = parseUint[uint32](value, ErrSaltTooLong)
+	default:
+		err = ErrDecodingFail
+	}
+	return err
+}
+
+// parseMemory parses a memory cost in KiB, optionally followed by one of
+// memoryUnits.
+func parseMemory(s string) (uint32, error) {
+	i := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+	if i < 0 {
+		i = len(s)
+	}
+
+	unit, ok := memoryUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if !ok {
+		return 0, ErrDecodingFail
+	}
+
+	n, err := parseUint[uint64](s[:i], ErrMemoryTooMuch)
+	if err != nil {
+		return 0, err
+	}
+	if n > math.MaxUint32/unit {
+		return 0, ErrMemoryTooMuch
+	}
+	return uint32(n * unit), nil
+}
+
+// parseUint parses a decimal integer, returning `tooLarge` if it overflows
+// T.
+func parseUint[T uint8 | uint32 | uint64](s string, tooLarge error) (T, error) {
+	n, err := strconv.ParseUint(s, 10, 64)
+	switch {
+	case errors.Is(err, strconv.ErrRange):
+		return 0, tooLarge
+	case err != nil:
+		return 0, ErrDecodingFail
+	case n > uint64(^T(0)):
+		return 0, tooLarge
+	}
+	return T(n), nil
+}
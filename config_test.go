@@ -0,0 +1,265 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package argon2_test
+
+import (
+	"errors"
+	"flag"
+	"testing"
+
+	"github.com/matthewhartstonge/argon2"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		input string
+		want  argon2.Config
+	}{
+		{
+			input: "argon2id,m=65536,t=3,p=4",
+			want:  argon2.DefaultConfig(),
+		},
+		{
+			input: "argon2i,v=16,m=64MiB,t=2,p=1,threads=1,hash-len=64,salt-len=32",
+			want: argon2.Config{
+				HashLength:  64,
+				SaltLength:  32,
+				TimeCost:    2,
+				MemoryCost:  65536,
+				Parallelism: 1,
+				Threads:     1,
+				Mode:        argon2.ModeArgon2i,
+				Version:     argon2.Version10,
+			},
+		},
+		{
+			input: "m=2GiB,t=1",
+			want: argon2.Config{
+				HashLength:  32,
+				SaltLength:  16,
+				TimeCost:    1,
+				MemoryCost:  2 * 1024 * 1024,
+				Parallelism: 4,
+				Mode:        argon2.ModeArgon2id,
+				Version:     argon2.Version13,
+			},
+		},
+		{
+			input: "Argon2d,m=512kib",
+			want: argon2.Config{
+				HashLength:  32,
+				SaltLength:  16,
+				TimeCost:    3,
+				MemoryCost:  512,
+				Parallelism: 4,
+				Mode:        argon2.ModeArgon2d,
+				Version:     argon2.Version13,
+			},
+		},
+		{
+			input: "owasp-2023",
+			want: argon2.Config{
+				HashLength:  32,
+				SaltLength:  16,
+				TimeCost:    2,
+				MemoryCost:  19456,
+				Parallelism: 1,
+				Mode:        argon2.ModeArgon2id,
+				Version:     argon2.Version13,
+			},
+		},
+		{
+			input: "php,m=1G",
+			want: argon2.Config{
+				HashLength:  32,
+				SaltLength:  16,
+				TimeCost:    4,
+				MemoryCost:  1024 * 1024,
+				Parallelism: 1,
+				Mode:        argon2.ModeArgon2id,
+				Version:     argon2.Version13,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := argon2.ParseConfig(tt.input)
+			if err != nil {
+				t.Fatalf("ParseConfig() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseConfig() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseConfigError(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr error
+	}{
+		{input: "", wantErr: argon2.ErrMissingArgs},
+		{input: "argon2x,m=65536", wantErr: argon2.ErrIncorrectType},
+		{input: "m=65536,argon2id", wantErr: argon2.ErrDecodingFail},
+		{input: "argon2id,x=1", wantErr: argon2.ErrDecodingFail},
+		{input: "argon2id,v=20", wantErr: argon2.ErrIncorrectType},
+		{input: "argon2id,m=64PiB", wantErr: argon2.ErrDecodingFail},
+		{input: "argon2id,m=MiB", wantErr: argon2.ErrDecodingFail},
+		{input: "argon2id,m=4TiB", wantErr: argon2.ErrMemoryTooMuch},
+		{input: "argon2id,m=4294967296", wantErr: argon2.ErrMemoryTooMuch},
+		{input: "argon2id,t=-1", wantErr: argon2.ErrDecodingFail},
+		{input: "argon2id,t=0", wantErr: argon2.ErrTimeTooSmall},
+		{input: "argon2id,t=4294967296", wantErr: argon2.ErrTimeTooLarge},
+		{input: "argon2id,p=256", wantErr: argon2.ErrLanesTooMany},
+		{input: "argon2id,p=0", wantErr: argon2.ErrLanesTooFew},
+		{input: "argon2id,threads=256", wantErr: argon2.ErrThreadsTooMany},
+		{input: "argon2id,hash-len=3", wantErr: argon2.ErrOutputTooShort},
+		{input: "argon2id,hash-len=99999999999999999999", wantErr: argon2.ErrOutputTooLong},
+		{input: "argon2id,salt-len=4294967296", wantErr: argon2.ErrSaltTooLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := argon2.ParseConfig(tt.input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ParseConfig() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigString(t *testing.T) {
+	zeroVersion := argon2.DefaultConfig()
+	zeroVersion.Version = 0
+
+	tests := []struct {
+		c    argon2.Config
+		want string
+		// parsed is the config ParseConfig returns, if it differs from c.
+		parsed argon2.Config
+	}{
+		{c: argon2.DefaultConfig(), want: "argon2id,m=65536,t=3,p=4"},
+		{c: argon2.RecommendedDefaults(), want: "argon2id,m=2097152,t=1,p=4"},
+		{
+			c: argon2.Config{
+				HashLength:  64,
+				SaltLength:  8,
+				TimeCost:    2,
+				MemoryCost:  1024,
+				Parallelism: 2,
+				Threads:     1,
+				Mode:        argon2.ModeArgon2d,
+				Version:     argon2.Version10,
+			},
+			want: "argon2d,v=16,m=1024,t=2,p=2,threads=1,hash-len=64,salt-len=8",
+		},
+		{
+			c:      zeroVersion,
+			want:   "argon2id,m=65536,t=3,p=4",
+			parsed: argon2.DefaultConfig(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.c.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+
+			c, err := argon2.ParseConfig(tt.c.String())
+			if err != nil {
+				t.Fatalf("ParseConfig() error = %v", err)
+			}
+			want := tt.c
+			if tt.parsed != (argon2.Config{}) {
+				want = tt.parsed
+			}
+			if c != want {
+				t.Errorf("ParseConfig(String()) = %#v, want %#v", c, want)
+			}
+		})
+	}
+}
+
+func TestConfigSet(t *testing.T) {
+	c := argon2.RecommendedDefaults()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&c, "argon2", "argon2 parameters")
+	if err := fs.Parse([]string{"-argon2", "m=64MiB,t=3", "-argon2", "p=2"}); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	want := "argon2id,m=65536,t=3,p=2"
+	if got := c.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	if err := c.Set("m=64MiB,t=0"); !errors.Is(err, argon2.ErrTimeTooSmall) {
+		t.Errorf("Set() error = %v, want %v", err, argon2.ErrTimeTooSmall)
+	}
+	if got := c.String(); got != want {
+		t.Errorf("Set() changed the config on error: %q, want %q", got, want)
+	}
+}
+
+func TestConfigLoadEnv(t *testing.T) {
+	t.Setenv("ARGON2_CONFIG", "owasp-2023,p=2")
+	t.Setenv("ARGON2_MODE", "argon2i")
+	t.Setenv("ARGON2_MEMORY", "64MiB")
+	t.Setenv("ARGON2_TIME", "4")
+	t.Setenv("ARGON2_HASH_LENGTH", "64")
+
+	c := argon2.DefaultConfig()
+	if err := c.LoadEnv("ARGON2"); err != nil {
+		t.Fatalf("LoadEnv() error = %v", err)
+	}
+
+	want := "argon2i,m=65536,t=4,p=2,hash-len=64"
+	if got := c.String(); got != want {
+		t.Errorf("LoadEnv() = %q, want %q", got, want)
+	}
+}
+
+func TestConfigLoadEnvError(t *testing.T) {
+	tests := []struct {
+		name, value string
+		wantErr     error
+	}{
+		{name: "APP_CONFIG", value: "argon2x", wantErr: argon2.ErrIncorrectType},
+		{name: "APP_MODE", value: "bcrypt", wantErr: argon2.ErrIncorrectType},
+		{name: "APP_VERSION", value: "2", wantErr: argon2.ErrIncorrectType},
+		{name: "APP_MEMORY", value: "lots", wantErr: argon2.ErrDecodingFail},
+		{name: "APP_TIME", value: "0", wantErr: argon2.ErrTimeTooSmall},
+		{name: "APP_PARALLELISM", value: "256", wantErr: argon2.ErrLanesTooMany},
+		{name: "APP_THREADS", value: "256", wantErr: argon2.ErrThreadsTooMany},
+		{name: "APP_SALT_LENGTH", value: "x", wantErr: argon2.ErrDecodingFail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.name, tt.value)
+
+			c := argon2.DefaultConfig()
+			err := c.LoadEnv("APP")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("LoadEnv() error = %v, want %v", err, tt.wantErr)
+			}
+			if c != argon2.DefaultConfig() {
+				t.Errorf("LoadEnv() changed the config on error: %v", c)
+			}
+		})
+	}
+}
@@ -0,0 +1,319 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package argon2
+
+// The in-package Argon2 core, following RFC 9106. It is based on
+// golang.org/x/crypto/argon2, which only implements Argon2i and Argon2id
+// version 1.3, always spawns one goroutine per lane and doesn't accept a
+// secret or associated data.
+//
+// Refer: https://www.rfc-editor.org/rfc/rfc9106.html
+
+import (
+	"encoding/binary"
+	"hash"
+	"sync"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	blockLength = 128
+	syncPoints  = 4
+)
+
+// block is a 1 KiB Argon2 memory block.
+type block [blockLength]uint64
+
+// params contains the inputs to a single Argon2 computation.
+type params struct {
+	mode    Mode
+	version Version
+	pwd     []byte
+	salt    []byte
+	secret  []byte
+	data    []byte
+	time    uint32
+	memory  uint32
+	lanes   uint32
+	threads uint32
+	keyLen  uint32
+	opts    *Options
+}
+
+// deriveKey computes an Argon2 hash using the in-package core. `p` must be
+// valid, see Config.validate.
+func deriveKey(p *params) []byte {
+	h0 := initHash(p)
+
+	memory := p.memory / (syncPoints * p.lanes) * (syncPoints * p.lanes)
+	if memory < 2*syncPoints*p.lanes {
+		memory = 2 * syncPoints * p.lanes
+	}
+
+	threads := p.threads
+	if threads == 0 || threads > p.lanes {
+		threads = p.lanes
+	}
+
+	B := initBlocks(&h0, memory, p.lanes)
+	processBlocks(B, p, memory, threads)
+	return extractKey(B, memory, p.lanes, p.keyLen)
+}
+
+// initHash computes the 64 byte pre-hashing digest H0, leaving 8 bytes of
+// room for the block and lane indices appended by initBlocks.
+func initHash(p *params) [blake2b.Size + 8]byte {
+	var (
+		h0   [blake2b.Size + 8]byte
+		args [24]byte
+		tmp  [4]byte
+	)
+
+	b2, _ := blake2b.New512(nil)
+	binary.LittleEndian.PutUint32(args[0:4], p.lanes)
+	binary.LittleEndian.PutUint32(args[4:8], p.keyLen)
+	binary.LittleEndian.PutUint32(args[8:12], p.memory)
+	binary.LittleEndian.PutUint32(args[12:16], p.time)
+	binary.LittleEndian.PutUint32(args[16:20], uint32(p.version))
+	binary.LittleEndian.PutUint32(args[20:24], uint32(p.mode))
+	b2.Write(args[:])
+	for _, in := range [][]byte{p.pwd, p.salt, p.secret, p.data} {
+		binary.LittleEndian.PutUint32(tmp[:], uint32(len(in)))
+		b2.Write(tmp[:])
+		b2.Write(in)
+	}
+	b2.Sum(h0[:0])
+
+	return h0
+}
+
+// initBlocks allocates the memory and computes the first two blocks of each
+// lane.
+func initBlocks(h0 *[blake2b.Size + 8]byte, memory, lanes uint32) []block {
+	var block0 [1024]byte
+	B := make([]block, memory)
+	for lane := uint32(0); lane < lanes; lane++ {
+		j := lane * (memory / lanes)
+		binary.LittleEndian.PutUint32(h0[blake2b.Size+4:], lane)
+
+		for i := uint32(0); i < 2; i++ {
+			binary.LittleEndian.PutUint32(h0[blake2b.Size:], i)
+			blake2bHash(block0[:], h0[:])
+			for k := range B[j+i] {
+				B[j+i][k] = binary.LittleEndian.Uint64(block0[k*8:])
+			}
+		}
+	}
+	return B
+}
+
+// processBlocks fills the memory, computing the segments of each slice on
+// at most `threads` goroutines.
+func processBlocks(B []block, p *params, memory, threads uint32) {
+	laneLength := memory / p.lanes
+	segmentLength := laneLength / syncPoints
+
+	processSegment := func(n, slice, lane uint32) {
+		var addresses, in, zero block
+
+		dataIndependent := p.mode == ModeArgon2i || (p.mode == ModeArgon2id && n == 0 && slice < syncPoints/2)
+		if dataIndependent {
+			in[0] = uint64(n)
+			in[1] = uint64(lane)
+			in[2] = uint64(slice)
+			in[3] = uint64(memory)
+			in[4] = uint64(p.time)
+			in[5] = uint64(p.mode)
+		}
+
+		index := uint32(0)
+		if n == 0 && slice == 0 {
+			index = 2 // the first two blocks have already been generated
+			if dataIndependent {
+				in[6]++
+				processBlock(&addresses, &in, &zero)
+				processBlock(&addresses, &addresses, &zero)
+			}
+		}
+
+		offset := lane*laneLength + slice*segmentLength + index
+		var random uint64
+		for index < segmentLength {
+			prev := offset - 1
+			if index == 0 && slice == 0 {
+				prev += laneLength // last block in lane
+			}
+			if dataIndependent {
+				if index%blockLength == 0 {
+					in[6]++
+					processBlock(&addresses, &in, &zero)
+					processBlock(&addresses, &addresses, &zero)
+				}
+				random = addresses[index%blockLength]
+			} else {
+				random = B[prev][0]
+			}
+
+			ref := indexAlpha(random, laneLength, segmentLength, p.lanes, n, slice, lane, index)
+			if n == 0 || p.version == Version10 {
+				// Version 1.0 overwrites blocks on every pass, while later
+				// versions xor the new block into the previous pass's.
+				processBlock(&B[offset], &B[prev], &B[ref])
+			} else {
+				processBlockXOR(&B[offset], &B[prev], &B[ref])
+			}
+			index, offset = index+1, offset+1
+		}
+	}
+
+	if p.opts != nil && p.opts.Progress != nil {
+		var (
+			mu        sync.Mutex
+			completed uint32
+			total     = p.time * syncPoints * p.lanes
+			fill      = processSegment
+		)
+		processSegment = func(n, slice, lane uint32) {
+			fill(n, slice, lane)
+
+			mu.Lock()
+			defer mu.Unlock()
+			completed++
+			p.opts.Progress(Progress{
+				Pass:      n,
+				Slice:     slice,
+				Lane:      lane,
+				Completed: completed,
+				Total:     total,
+			})
+		}
+	}
+
+	for n := uint32(0); n < p.time; n++ {
+		for slice := uint32(0); slice < syncPoints; slice++ {
+			if threads == 1 {
+				for lane := uint32(0); lane < p.lanes; lane++ {
+					processSegment(n, slice, lane)
+				}
+				continue
+			}
+
+			var wg sync.WaitGroup
+			for t := uint32(0); t < threads; t++ {
+				wg.Add(1)
+				go func(t uint32) {
+					defer wg.Done()
+					for lane := t; lane < p.lanes; lane += threads {
+						processSegment(n, slice, lane)
+					}
+				}(t)
+			}
+			wg.Wait()
+		}
+	}
+}
+
+// extractKey xors the last block of every lane and hashes the result into a
+// key of keyLen bytes.
+func extractKey(B []block, memory, lanes, keyLen uint32) []byte {
+	laneLength := memory / lanes
+	for lane := uint32(0); lane < lanes-1; lane++ {
+		for i, v := range B[(lane*laneLength)+laneLength-1] {
+			B[memory-1][i] ^= v
+		}
+	}
+
+	var out [1024]byte
+	for i, v := range B[memory-1] {
+		binary.LittleEndian.PutUint64(out[i*8:], v)
+	}
+	key := make([]byte, keyLen)
+	blake2bHash(key, out[:])
+	return key
+}
+
+// indexAlpha maps the pseudo-random value `rand` to the index of the block
+// referenced when computing block `index` of the given segment.
+func indexAlpha(rand uint64, laneLength, segmentLength, lanes, n, slice, lane, index uint32) uint32 {
+	refLane := uint32(rand>>32) % lanes
+	if n == 0 && slice == 0 {
+		refLane = lane
+	}
+	m, s := 3*segmentLength, ((slice+1)%syncPoints)*segmentLength
+	if lane == refLane {
+		m += index
+	}
+	if n == 0 {
+		m, s = slice*segmentLength, 0
+		if slice == 0 || lane == refLane {
+			m += index
+		}
+	}
+	if index == 0 || lane == refLane {
+		m--
+	}
+	return phi(rand, uint64(m), uint64(s), refLane, laneLength)
+}
+
+func phi(rand, m, s uint64, lane, laneLength uint32) uint32 {
+	p := rand & 0xFFFFFFFF
+	p = (p * p) >> 32
+	p = (p * m) >> 32
+	return lane*laneLength + uint32((s+m-(p+1))%uint64(laneLength))
+}
+
+// blake2bHash computes the variable length hash function H' of `in`,
+// writing len(out) bytes to `out`.
+func blake2bHash(out []byte, in []byte) {
+	var b2 hash.Hash
+	if n := len(out); n < blake2b.Size {
+		b2, _ = blake2b.New(n, nil)
+	} else {
+		b2, _ = blake2b.New512(nil)
+	}
+
+	var buffer [blake2b.Size]byte
+	binary.LittleEndian.PutUint32(buffer[:4], uint32(len(out)))
+	b2.Write(buffer[:4])
+	b2.Write(in)
+
+	if len(out) <= blake2b.Size {
+		b2.Sum(out[:0])
+		return
+	}
+
+	outLen := len(out)
+	b2.Sum(buffer[:0])
+	b2.Reset()
+	copy(out, buffer[:32])
+	out = out[32:]
+	for len(out) > blake2b.Size {
+		b2.Write(buffer[:])
+		b2.Sum(buffer[:0])
+		copy(out, buffer[:32])
+		out = out[32:]
+		b2.Reset()
+	}
+
+	if outLen%blake2b.Size > 0 { // outLen > 64
+		r := ((outLen + 31) / 32) - 2 // ⌈τ/32⌉-2
+		b2, _ = blake2b.New(outLen-32*r, nil)
+	}
+	b2.Write(buffer[:])
+	b2.Sum(out[:0])
+}
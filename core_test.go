@@ -0,0 +1,130 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package argon2
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// TestDeriveKey checks the core against hashes computed by the reference
+// implementation, libargon2, using the password "password" and salt
+// "somesaltsomesalt".
+func TestDeriveKey(t *testing.T) {
+	tests := []struct {
+		mode    Mode
+		version Version
+		time    uint32
+		memory  uint32
+		lanes   uint32
+		want    string
+	}{
+		{mode: ModeArgon2d, version: Version10, time: 1, memory: 64, lanes: 1, want: "e69987878104852e1141807e2c9c4c597f23d221a5959f515b4c1a0750ae2aee"},
+		{mode: ModeArgon2d, version: Version10, time: 3, memory: 256, lanes: 4, want: "231179a5b262e03cbe14908dae95e5266b596c0786c20b304fa363f5fd5f5f23"},
+		{mode: ModeArgon2d, version: Version10, time: 2, memory: 100, lanes: 3, want: "95d9f5e2ab34b5fcc6b31edf4d8a2949615052d49921ca9f36249147a96cd8a7"},
+		{mode: ModeArgon2d, version: Version13, time: 1, memory: 64, lanes: 1, want: "3c1b1b109bda5b8a65aaa54f3259da58fdaebbeea7910c6cce27729f8110c4ac"},
+		{mode: ModeArgon2d, version: Version13, time: 3, memory: 256, lanes: 4, want: "b1c8cb708404f2cdb6bd87c6c6362eb2778d58637fef16fb8cb0fff4662e65f9"},
+		{mode: ModeArgon2d, version: Version13, time: 2, memory: 100, lanes: 3, want: "70fa478d69791ffd2ffa6bba92f4fe49b9d9eaeef1828c6381d0485b9ac86e43"},
+		{mode: ModeArgon2i, version: Version10, time: 1, memory: 64, lanes: 1, want: "03535fa7428475b3d0680a2444c35bff1d5c78fa7fdea1cdecb205e51d8ea233"},
+		{mode: ModeArgon2i, version: Version10, time: 3, memory: 256, lanes: 4, want: "009f0260caa68e7c3f5c77895f17c081584ae04107f1ec7f88d1f30325736c98"},
+		{mode: ModeArgon2i, version: Version10, time: 2, memory: 100, lanes: 3, want: "ae734e1586f37e5b55be0b4927bf965c8fd3021562ee32e2711dc310bc8351b8"},
+		{mode: ModeArgon2i, version: Version13, time: 1, memory: 64, lanes: 1, want: "efb5f53ad447f68971dc6f665c7249cca5aa5f16dcd6c5f20314057ed924ed53"},
+		{mode: ModeArgon2i, version: Version13, time: 3, memory: 256, lanes: 4, want: "bc1a8513782c0131032fbb6c1370198990e2eb1d757e6ce31c3635d504a43e79"},
+		{mode: ModeArgon2i, version: Version13, time: 2, memory: 100, lanes: 3, want: "f56d694bc52dc8cb78690afe3831d23a8c1298cc878c5945a50ccbdccd6f0b1a"},
+		{mode: ModeArgon2id, version: Version10, time: 1, memory: 64, lanes: 1, want: "8c1dcec96a42076c7f0e93076f57e0aff9e03a18c11ed2abf36f556b0eb9afd6"},
+		{mode: ModeArgon2id, version: Version10, time: 3, memory: 256, lanes: 4, want: "40f89c4fce1c63a1dd80062d732267ebb3cc37725a6dfec70ef4c36771622e6a"},
+		{mode: ModeArgon2id, version: Version10, time: 2, memory: 100, lanes: 3, want: "3c2e847b28f39bf252593adb4ac20c16e68cca3b5ff10cc2beddcd9b9ccea64e"},
+		{mode: ModeArgon2id, version: Version13, time: 1, memory: 64, lanes: 1, want: "e793d64ef75d58f503d4631b2b149f7f80127c5f3993d89b1c9b781e51d0b413"},
+		{mode: ModeArgon2id, version: Version13, time: 3, memory: 256, lanes: 4, want: "3ae75675fc1875866526c8f930cb40d6fe751ec0bea5ad3052bafca5369373b3"},
+		{mode: ModeArgon2id, version: Version13, time: 2, memory: 100, lanes: 3, want: "b2e09be5fde45e9e39e720f17158c86353c3455f75a01f3cc290f14f6dda51a7"},
+	}
+
+	for _, tt := range tests {
+		for _, threads := range []uint32{0, 1, 2} {
+			name := fmt.Sprintf("%s/v=%s/m=%d,t=%d,p=%d/threads=%d", tt.mode, tt.version, tt.memory, tt.time, tt.lanes, threads)
+			t.Run(name, func(t *testing.T) {
+				got := deriveKey(&params{
+					mode:    tt.mode,
+					version: tt.version,
+					pwd:     []byte("password"),
+					salt:    []byte("somesaltsomesalt"),
+					time:    tt.time,
+					memory:  tt.memory,
+					lanes:   tt.lanes,
+					threads: threads,
+					keyLen:  32,
+				})
+				if hex.EncodeToString(got) != tt.want {
+					t.Errorf("deriveKey() = %x, want %s", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+// TestDeriveKeyXCrypto checks the core against x/crypto for output lengths
+// exercising every branch of blake2bHash.
+func TestDeriveKeyXCrypto(t *testing.T) {
+	pwd, salt := []byte("password"), []byte("somesaltsomesalt")
+	for _, keyLen := range []uint32{4, 32, 64, 65, 96, 100, 1024} {
+		want := argon2.IDKey(pwd, salt, 2, 64, 2, keyLen)
+		got := deriveKey(&params{
+			mode:    ModeArgon2id,
+			version: Version13,
+			pwd:     pwd,
+			salt:    salt,
+			time:    2,
+			memory:  64,
+			lanes:   2,
+			threads: 1,
+			keyLen:  keyLen,
+		})
+		if !bytes.Equal(got, want) {
+			t.Errorf("keyLen=%d: deriveKey() = %x, want %x", keyLen, got, want)
+		}
+	}
+}
+
+// BenchmarkDeriveKey compares the core against x/crypto.
+func BenchmarkDeriveKey(b *testing.B) {
+	pwd, salt := []byte("password"), []byte("somesaltsomesalt")
+	p := &params{
+		mode:    ModeArgon2id,
+		version: Version13,
+		pwd:     pwd,
+		salt:    salt,
+		time:    1,
+		memory:  32 * 1024,
+		lanes:   1,
+		keyLen:  32,
+	}
+
+	b.Run("core", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			deriveKey(p)
+		}
+	})
+	b.Run("xcrypto", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			argon2.IDKey(pwd, salt, p.time, p.memory, uint8(p.lanes), p.keyLen)
+		}
+	})
+}
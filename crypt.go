@@ -0,0 +1,85 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package argon2
+
+import "fmt"
+
+// cryptHashLength is the hash length used by Crypt when the setting doesn't
+// include a hash, matching the reference implementation's default.
+const cryptHashLength = 32
+
+// GenSalt returns a crypt(3) style setting for `c`, the encoded hash
+// up to and including a salt of Config.SaltLength bytes read from
+// Options.Rand, e.g. "$argon2id$v=19$m=65536,t=1,p=4$c29tZXNhbHQ".
+//
+// Pass the setting to Crypt to hash a password. As the setting has no room
+// for the hash length, Crypt always produces 32 byte hashes from it, and
+// configs with a different Config.HashLength are rejected.
+func GenSalt(c Config) ([]byte, error) {
+	c = *c.withDefaults()
+	if err := c.validate(nil, nil); err != nil {
+		return nil, err
+	}
+	if c.HashLength != cryptHashLength {
+		err := ErrOutputTooShort
+		if c.HashLength > cryptHashLength {
+			err = ErrOutputTooLong
+		}
+		return nil, fmt.Errorf("argon2: crypt settings produce %d byte hashes: %w", cryptHashLength, err)
+	}
+
+	salt, err := c.NewSalt()
+	if err != nil {
+		return nil, err
+	}
+	raw := Raw{
+		Config: c,
+		Salt:   salt,
+	}
+
+	enc := raw.Encode()
+	return enc[:len(enc)-1], nil // drop the '$' delimiting the empty hash
+}
+
+// Crypt hashes `password` with the parameters and salt of `setting`,
+// returning the encoded hash, like crypt(3).
+//
+// `setting` is either generated by GenSalt or is a previously encoded hash,
+// in which case its hash length is kept and the password is verified by
+// comparing the result to `setting` with subtle.ConstantTimeCompare.
+// Otherwise, the hash is 32 bytes long.
+func Crypt(password, setting []byte) ([]byte, error) {
+	raw, err := decode(setting, false)
+	if err != nil {
+		return nil, err
+	}
+	if raw.Config.HashLength == 0 {
+		raw.Config.HashLength = cryptHashLength
+	}
+
+	pwd, err := raw.unwrap(password)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := raw.Config.Hash(pwd, raw.Salt)
+	if err != nil {
+		return nil, err
+	}
+	r.Wrap, r.WrapParams = raw.Wrap, raw.WrapParams
+
+	return r.Encode(), nil
+}
@@ -0,0 +1,169 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package argon2_test
+
+import (
+	"bytes"
+	"crypto/md5" //nolint:gosec // testing legacy md5 hashes.
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/matthewhartstonge/argon2"
+)
+
+func TestCrypt(t *testing.T) {
+	cfg := deterministicConfig()
+	setting, err := argon2.GenSalt(cfg)
+	mustBeFalsey(t, "err", err)
+
+	wantSetting := "$argon2id$v=19$m=32768,t=1,p=1$AAECAwQFBgcICQoLDA0ODw"
+	if string(setting) != wantSetting {
+		t.Fatalf("GenSalt() = %s, want %s", setting, wantSetting)
+	}
+
+	want := wantSetting + "$3CjPy6pyUZBujrCkIH+QMV0IUUWC4Zggi/m9sk731mo"
+	enc, err := argon2.Crypt(password, setting)
+	mustBeFalsey(t, "err", err)
+	if string(enc) != want {
+		t.Fatalf("Crypt() = %s, want %s", enc, want)
+	}
+
+	enc, err = argon2.Crypt(password, []byte(want))
+	mustBeFalsey(t, "err", err)
+	if string(enc) != want {
+		t.Errorf("Crypt(password, hash) = %s, want %s", enc, want)
+	}
+
+	enc, err = argon2.Crypt([]byte("wrong"), []byte(want))
+	mustBeFalsey(t, "err", err)
+	if string(enc) == want {
+		t.Error("Crypt() should produce a different hash for the wrong password")
+	}
+}
+
+func TestCryptHashLength(t *testing.T) {
+	cfg := config
+	cfg.HashLength = 16
+	r, err := cfg.Hash(password, salt)
+	mustBeFalsey(t, "err", err)
+
+	want := r.Encode()
+	enc, err := argon2.Crypt(password, want)
+	mustBeFalsey(t, "err", err)
+	if !bytes.Equal(enc, want) {
+		t.Errorf("Crypt() = %s, want %s", enc, want)
+	}
+}
+
+func TestCryptWrapped(t *testing.T) {
+	md5Sum := md5.Sum(password) //nolint:gosec // testing legacy md5 hashes.
+	r, err := config.HashWrapped([]byte(hex.EncodeToString(md5Sum[:])), "md5")
+	mustBeFalsey(t, "err", err)
+
+	want := r.Encode()
+	enc, err := argon2.Crypt(password, want)
+	mustBeFalsey(t, "err", err)
+	if !bytes.Equal(enc, want) {
+		t.Errorf("Crypt() = %s, want %s", enc, want)
+	}
+}
+
+func TestGenSaltError(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(c *argon2.Config)
+		wantErr error
+	}{
+		{
+			name:    "no salt",
+			mutate:  func(c *argon2.Config) { c.SaltLength = 0 },
+			wantErr: argon2.ErrSaltTooShort,
+		},
+		{
+			name:    "unknown mode",
+			mutate:  func(c *argon2.Config) { c.Mode = 3 },
+			wantErr: argon2.ErrIncorrectType,
+		},
+		{
+			name:    "no time cost",
+			mutate:  func(c *argon2.Config) { c.TimeCost = 0 },
+			wantErr: argon2.ErrTimeTooSmall,
+		},
+		{
+			name:    "short hash",
+			mutate:  func(c *argon2.Config) { c.HashLength = 16 },
+			wantErr: argon2.ErrOutputTooShort,
+		},
+		{
+			name:    "long hash",
+			mutate:  func(c *argon2.Config) { c.HashLength = 64 },
+			wantErr: argon2.ErrOutputTooLong,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config
+			tt.mutate(&cfg)
+			if _, err := argon2.GenSalt(cfg); !errors.Is(err, tt.wantErr) {
+				t.Errorf("GenSalt() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCryptError(t *testing.T) {
+	tests := []struct {
+		name    string
+		setting string
+		wantErr error
+	}{
+		{
+			name:    "bcrypt",
+			setting: "$2y$10$B93GqMy3DNkIvyLbsxgtFO",
+			wantErr: argon2.ErrIncorrectType,
+		},
+		{
+			name:    "missing salt",
+			setting: "$argon2id$v=19$m=32768,t=1,p=1",
+			wantErr: argon2.ErrDecodingFail,
+		},
+		{
+			name:    "empty salt",
+			setting: "$argon2id$v=19$m=32768,t=1,p=1$$3CjPy6pyUZBujrCkIH+QMV0IUUWC4Zggi/m9sk731mo",
+			wantErr: argon2.ErrDecodingFail,
+		},
+		{
+			name:    "invalid salt",
+			setting: "$argon2id$v=19$m=32768,t=1,p=1$!!invalid!!",
+			wantErr: argon2.ErrDecodingFail,
+		},
+		{
+			name:    "hash too short",
+			setting: "$argon2id$v=19$m=32768,t=1,p=1$AAECAwQFBgcICQoLDA0ODw$AAA",
+			wantErr: argon2.ErrOutputTooShort,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := argon2.Crypt(password, []byte(tt.setting)); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Crypt() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
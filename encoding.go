@@ -29,7 +29,7 @@ type parser struct {
 	off int
 }
 
-// Ensures that the next len(b) bytes match b
+// Ensures that the next len(b) bytes match b, returning 0 if they do.
 func (p *parser) check(b []byte) int {
 	l := len(b)
 	i := p.off
@@ -40,7 +40,7 @@ func (p *parser) check(b []byte) int {
 		return bytes.Compare(b, p.buf[i:j])
 	}
 
-	return 0
+	return -1
 }
 
 // Reads a single byte or returns 0
@@ -65,12 +65,12 @@ func (p *parser) parseUint8() uint8 {
 		d := p.buf[i]
 
 		if '0' <= d && d <= '9' {
-			rb := r
-			r = r*10 + uint8(d-'0')
-
-			if r < rb {
+			n := uint8(d - '0')
+			if r > (math.MaxUint8-n)/10 {
 				return 0 // integer overflow
 			}
+
+			r = r*10 + n
 		} else {
 			break
 		}
@@ -91,12 +91,12 @@ func (p *parser) parseUint32() uint32 {
 		d := p.buf[i]
 
 		if '0' <= d && d <= '9' {
-			rb := r
-			r = r*10 + uint32(d-'0')
-
-			if r < rb {
+			n := uint32(d - '0')
+			if r > (math.MaxUint32-n)/10 {
 				return 0 // integer overflow
 			}
+
+			r = r*10 + n
 		} else {
 			break
 		}
@@ -106,21 +106,25 @@ func (p *parser) parseUint32() uint32 {
 	return r
 }
 
-// Skips 0 or more bytes until delim is found (the skip includes delim).
-func (p *parser) skipUntil(delim byte) {
+// Skips 0 or more bytes until delim is found (the skip includes delim) and
+// returns the skipped bytes (without delim).
+func (p *parser) skipUntil(delim byte) []byte {
 	i := p.off
 	idx := bytes.IndexByte(p.buf[i:], delim)
 
 	if idx >= 0 {
 		p.off = i + idx + 1
+		return p.buf[i : i+idx]
 	}
+
+	return nil
 }
 
 // Does the same as skipUntil(delim), but returns a slice of the skipped
 // bytes (without delim). Returns nil if the slice length is less than 1.
 func (p *parser) readSlice(delim byte) []byte {
 	i := p.off
-	idx := bytes.IndexByte(p.buf[i:], delim) // TODO: len()
+	idx := bytes.IndexByte(p.buf[i:], delim)
 
 	if idx > 0 {
 		j := i + idx
@@ -186,6 +190,8 @@ var (
 	encTypD     = []byte("d$v=")
 	encTypI     = []byte("i$v=")
 	encTypID    = []byte("id$v=")
+	encWrap     = []byte(",wrap=")
+	encWrapArgs = []byte(",wrapparams=")
 )
 
 // Encode turns a Raw struct into the official stringified/encoded argon2
@@ -209,7 +215,7 @@ func (raw *Raw) Encode() []byte {
 	var encTyp []byte
 
 	switch c.Mode {
-	case modeArgon2d:
+	case ModeArgon2d:
 		encTyp = encTypD
 	case ModeArgon2i:
 		encTyp = encTypI
@@ -226,6 +232,14 @@ func (raw *Raw) Encode() []byte {
 	buf = strconv.AppendUint(buf, uint64(c.TimeCost), 10)
 	buf = append(buf, decParallel...)
 	buf = strconv.AppendUint(buf, uint64(c.Parallelism), 10)
+	if raw.Wrap != "" {
+		buf = append(buf, encWrap...)
+		buf = append(buf, raw.Wrap...)
+		if len(raw.WrapParams) > 0 {
+			buf = append(buf, encWrapArgs...)
+			buf = appendBase64(buf, raw.WrapParams, 0)
+		}
+	}
 	buf = append(buf, '$')
 	buf = appendBase64(buf, raw.Salt, saltLen64)
 	buf = append(buf, '$')
@@ -239,6 +253,13 @@ func (raw *Raw) Encode() []byte {
 //
 // This decoder ignores "data" attributes as they are likely to be deprecated.
 func Decode(encoded []byte) (Raw, error) {
+	return decode(encoded, true)
+}
+
+// decode implements Decode. Unless `needHash` is set, the hash may be
+// omitted, as in a crypt(3) setting, in which case Config.HashLength is left
+// as 0.
+func decode(encoded []byte, needHash bool) (Raw, error) {
 	pa := &parser{buf: encoded}
 
 	if pa.check(decPrefix) != 0 {
@@ -258,25 +279,36 @@ func Decode(encoded []byte) (Raw, error) {
 	t := pa.parseUint32()
 	ok |= pa.check(decParallel)
 	p := pa.parseUint8()
-	pa.skipUntil('$')
+	wrap, wrapParams, wrapOk := decodeWrap(pa.skipUntil('$'))
 	s := pa.readSlice('$')
 	h := pa.readRest()
+	if !needHash && s == nil && h != nil && h[0] != '$' {
+		s, h = h, nil
+	}
 
-	if ok != 0 || v == 0 || v > 255 || m == 0 || t == 0 || p == 0 || s == nil || h == nil {
+	if ok != 0 || v == 0 || v > 255 || m == 0 || t == 0 || p == 0 || s == nil || (needHash && h == nil) || !wrapOk {
 		return Raw{}, ErrDecodingFail
 	}
 
 	salt := make([]byte, enc64.DecodedLen(len(s)))
-	hash := make([]byte, enc64.DecodedLen(len(h)))
 	sl, se := enc64.Decode(salt, s)
-	hl, he := enc64.Decode(hash, h)
-	if se != nil || he != nil || sl <= 0 || hl <= 0 || uint64(sl) > math.MaxUint32 || uint64(hl) > math.MaxUint32 {
+	if se != nil || sl <= 0 || uint64(sl) > math.MaxUint32 {
 		return Raw{}, ErrDecodingFail
 	}
 
+	var hash []byte
+	if h != nil {
+		hash = make([]byte, enc64.DecodedLen(len(h)))
+		hl, he := enc64.Decode(hash, h)
+		if he != nil || hl <= 0 || uint64(hl) > math.MaxUint32 {
+			return Raw{}, ErrDecodingFail
+		}
+		hash = hash[0:hl]
+	}
+
 	return Raw{
 		Config: Config{
-			HashLength:  uint32(hl),
+			HashLength:  uint32(len(hash)),
 			SaltLength:  uint32(sl),
 			MemoryCost:  m,
 			TimeCost:    t,
@@ -284,11 +316,50 @@ func Decode(encoded []byte) (Raw, error) {
 			Mode:        mode,
 			Version:     Version(v),
 		},
-		Salt: salt[0:sl],
-		Hash: hash[0:hl],
+		Salt:       salt[0:sl],
+		Hash:       hash,
+		Wrap:       wrap,
+		WrapParams: wrapParams,
 	}, nil
 }
 
+// decodeWrap extracts the optional "wrap" and "wrapparams" values from the
+// parameters trailing ",p=<parallelism>". Any other parameters, such as the
+// deprecated "data" attribute, are ignored.
+func decodeWrap(extra []byte) (wrap string, params []byte, ok bool) {
+	if len(extra) == 0 {
+		return "", nil, true
+	}
+	if extra[0] != ',' {
+		return "", nil, false
+	}
+
+	for _, param := range bytes.Split(extra[1:], []byte(",")) {
+		key, value, _ := bytes.Cut(param, []byte("="))
+		switch string(key) {
+		case "wrap":
+			if !validWrapName(value) {
+				return "", nil, false
+			}
+			wrap = string(value)
+
+		case "wrapparams":
+			params = make([]byte, enc64.DecodedLen(len(value)))
+			n, err := enc64.Decode(params, value)
+			if err != nil || n == 0 {
+				return "", nil, false
+			}
+			params = params[:n]
+		}
+	}
+
+	if params != nil && wrap == "" {
+		return "", nil, false
+	}
+
+	return wrap, params, true
+}
+
 // checkMode returns the parsed argon2 mode, or an error.
 func checkMode(pa *parser) (mode Mode, err error) {
 	typ1 := pa.readByte()
@@ -307,7 +378,9 @@ func checkMode(pa *parser) (mode Mode, err error) {
 		}
 
 	case 'd':
-		return modeArgon2d, nil
+		if typ2 == '$' {
+			return ModeArgon2d, nil
+		}
 	}
 
 	return mode, ErrIncorrectType
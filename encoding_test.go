@@ -17,7 +17,11 @@
 package argon2
 
 import (
+	"bytes"
 	"errors"
+	"reflect"
+	"regexp"
+	"strconv"
 	"testing"
 )
 
@@ -118,6 +122,14 @@ func Test_parser_parseUint8(t *testing.T) {
 			},
 			want: 0,
 		},
+		{
+			name: "should parse uint8 overflow wrapping above the previous digits as zero",
+			fields: fields{
+				buf: []byte("300"),
+				off: 0,
+			},
+			want: 0,
+		},
 		{
 			name: "should parse uint8 128",
 			fields: fields{
@@ -140,6 +152,40 @@ func Test_parser_parseUint8(t *testing.T) {
 	}
 }
 
+func Test_parser_parseUint32(t *testing.T) {
+	tests := []struct {
+		input string
+		want  uint32
+	}{
+		{input: "0", want: 0},
+		{input: "65536", want: 65536},
+		{input: "4294967295", want: 4294967295},
+		{input: "4294967296", want: 0},
+		{input: "9999999999", want: 0},
+		{input: "42949672950", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			p := &parser{buf: []byte(tt.input)}
+			if got := p.parseUint32(); got != tt.want {
+				t.Errorf("parser.parseUint32() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parser_check(t *testing.T) {
+	p := &parser{buf: []byte("$argon")}
+	if p.check(decPrefix) == 0 {
+		t.Error("parser.check() should fail if the buffer is too short")
+	}
+
+	p = &parser{buf: []byte("$argon2id")}
+	if p.check(decPrefix) != 0 || p.off != len(decPrefix) {
+		t.Errorf("parser.check() should match and advance, got offset %d", p.off)
+	}
+}
+
 func Test_checkMode(t *testing.T) {
 	type args struct {
 		pa *parser
@@ -157,7 +203,7 @@ func Test_checkMode(t *testing.T) {
 					buf: []byte("0$"),
 				},
 			},
-			wantMode: modeArgon2d,
+			wantMode: ModeArgon2d,
 			wantErr:  true,
 		},
 		{
@@ -167,7 +213,17 @@ func Test_checkMode(t *testing.T) {
 					buf: []byte("argon2d$"),
 				},
 			},
-			wantMode: modeArgon2d,
+			wantMode: ModeArgon2d,
+			wantErr:  true,
+		},
+		{
+			name: "should error parsing argon2d mode without delimiter",
+			args: args{
+				pa: &parser{
+					buf: []byte("dx"),
+				},
+			},
+			wantMode: ModeArgon2d,
 			wantErr:  true,
 		},
 		{
@@ -177,7 +233,7 @@ func Test_checkMode(t *testing.T) {
 					buf: []byte("d$"),
 				},
 			},
-			wantMode: modeArgon2d,
+			wantMode: ModeArgon2d,
 			wantErr:  false,
 		},
 		{
@@ -214,3 +270,79 @@ func Test_checkMode(t *testing.T) {
 		})
 	}
 }
+
+// decodeSeeds seeds the Decode fuzz targets, in addition to testdata/fuzz.
+var decodeSeeds = []string{
+	"$argon2i$v=19$m=16,t=2,p=1$MmpQV3BIRTVnOVZHOFBISQ$VDE34WMQOVG4X6Sqje0gkg",
+	"$argon2d$v=19$m=16,t=2,p=1$MmpQV3BIRTVnOVZHOFBISQ$NyuqklQf+S+vzz3AtQCL8w",
+	"$argon2id$v=19$m=16,t=2,p=1$MmpQV3BIRTVnOVZHOFBISQ$zirDUv1ZjLw0/layHCmWmQ",
+	"$argon2id$v=19$m=65536,t=3,p=4,wrap=bcrypt,wrapparams=JDJiJDA0JA$c29tZXNhbHQ$RdescudvJCsgt3ub",
+	"$argon2id$v=19$m=65536,t=3,p=4,data=Zm9v$c29tZXNhbHQ$RdescudvJCsgt3ub",
+}
+
+// decodeParams matches the parameters of a valid encoded hash, to check
+// Decode against strconv.
+var decodeParams = regexp.MustCompile(`^\$argon2(id|i|d)\$v=([0-9]+)\$m=([0-9]+),t=([0-9]+),p=([0-9]+)[,$]`)
+
+func FuzzDecode(f *testing.F) {
+	for _, seed := range decodeSeeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, encoded []byte) {
+		r, err := Decode(encoded)
+		if err != nil {
+			return
+		}
+
+		m := decodeParams.FindSubmatch(encoded)
+		if m == nil {
+			t.Fatalf("Decode(%q) accepted a hash with malformed parameters", encoded)
+		}
+
+		want := map[string]struct {
+			got     uint64
+			bitSize int
+			text    []byte
+		}{
+			"v": {uint64(r.Config.Version), 32, m[2]},
+			"m": {uint64(r.Config.MemoryCost), 32, m[3]},
+			"t": {uint64(r.Config.TimeCost), 32, m[4]},
+			"p": {uint64(r.Config.Parallelism), 8, m[5]},
+		}
+		for name, v := range want {
+			n, err := strconv.ParseUint(string(v.text), 10, v.bitSize)
+			if err != nil || n != v.got {
+				t.Errorf("Decode(%q) %s = %d, want %s", encoded, name, v.got, v.text)
+			}
+		}
+	})
+}
+
+func FuzzDecodeEncode(f *testing.F) {
+	for _, seed := range decodeSeeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, encoded []byte) {
+		r, err := Decode(encoded)
+		if err != nil {
+			return
+		}
+
+		// Decoding is lenient, e.g. it ignores unknown parameters and
+		// leading zeros, so compare canonical encodings rather than the
+		// input.
+		enc := r.Encode()
+		r2, err := Decode(enc)
+		if err != nil {
+			t.Fatalf("Decode(Encode(Decode(%q))) error = %v", encoded, err)
+		}
+		if !reflect.DeepEqual(r, r2) {
+			t.Errorf("Decode(Encode(Decode(%q))) = %+v, want %+v", encoded, r2, r)
+		}
+		if enc2 := r2.Encode(); !bytes.Equal(enc, enc2) {
+			t.Errorf("Encode() isn't idempotent: got %q, want %q", enc2, enc)
+		}
+	})
+}
@@ -16,6 +16,8 @@
 
 package argon2
 
+import "strconv"
+
 // Error represents the error code returned by argon2.
 type Error string
 
@@ -23,6 +25,51 @@ func (e Error) Error() string {
 	return string(e)
 }
 
+// Code returns the libargon2 error code of `e`, one of the negative
+// ARGON2_* values, so errors can be transmitted across process or language
+// boundaries. Errors not defined by this package return the code of
+// ErrIncorrectParameter.
+func (e Error) Code() int {
+	if e == ErrModeUnsupported {
+		return ErrIncorrectType.Code()
+	}
+
+	for i, err := range errorCodes {
+		if i > 0 && err == e {
+			return -i
+		}
+	}
+
+	return ErrIncorrectParameter.Code()
+}
+
+// ErrorFromCode returns the error with the libargon2 error code `code`, such
+// that ErrorFromCode(err.Code()) == err. It returns nil for ARGON2_OK (0).
+//
+// Codes this package doesn't define return an error whose Code method
+// returns `code`, so they can be passed on unchanged.
+func ErrorFromCode(code int) error {
+	switch {
+	case code == 0:
+		return nil
+	case code < 0 && -code < len(errorCodes):
+		return errorCodes[-code]
+	}
+	return unknownError(code)
+}
+
+// unknownError is an error code not defined by libargon2.
+type unknownError int
+
+func (e unknownError) Error() string {
+	return "unknown error code " + strconv.Itoa(int(e))
+}
+
+// Code returns the error code.
+func (e unknownError) Code() int {
+	return int(e)
+}
+
 var (
 	ErrOutputPtrNull         = Error("output pointer is null")
 	ErrOutputTooShort        = Error("output is too short")
@@ -59,9 +106,51 @@ var (
 	ErrThreadFail            = Error("threading failure")
 	ErrDecodingLengthFail    = Error("some of encoded parameters are too long or too short")
 	ErrVerifyMismatch        = Error("the password does not match the supplied hash")
-	ErrModeUnsupported       = Error("argon2d hashing mode unsupported by go maintainers")
+	// Deprecated: ErrModeUnsupported is no longer returned, as argon2d
+	// hashes are computed by the package's own Argon2 core.
+	ErrModeUnsupported = Error("argon2d hashing mode unsupported by go maintainers")
 )
 
+// errorCodes lists the errors indexed by their negated libargon2 error code.
+var errorCodes = []Error{
+	0:  "",
+	1:  ErrOutputPtrNull,
+	2:  ErrOutputTooShort,
+	3:  ErrOutputTooLong,
+	4:  ErrPwdTooShort,
+	5:  ErrPwdTooLong,
+	6:  ErrSaltTooShort,
+	7:  ErrSaltTooLong,
+	8:  ErrAdTooShort,
+	9:  ErrAdTooLong,
+	10: ErrSecretTooShort,
+	11: ErrSecretTooLong,
+	12: ErrTimeTooSmall,
+	13: ErrTimeTooLarge,
+	14: ErrMemoryTooLittle,
+	15: ErrMemoryTooMuch,
+	16: ErrLanesTooFew,
+	17: ErrLanesTooMany,
+	18: ErrPwdPtrMismatch,
+	19: ErrSaltPtrMismatch,
+	20: ErrSecretPtrMismatch,
+	21: ErrAdPtrMismatch,
+	22: ErrMemoryAllocationError,
+	23: ErrFreeMemoryCbkNull,
+	24: ErrAllocateMemoryCbkNull,
+	25: ErrIncorrectParameter,
+	26: ErrIncorrectType,
+	27: ErrOutPtrMismatch,
+	28: ErrThreadsTooFew,
+	29: ErrThreadsTooMany,
+	30: ErrMissingArgs,
+	31: ErrEncodingFail,
+	32: ErrDecodingFail,
+	33: ErrThreadFail,
+	34: ErrDecodingLengthFail,
+	35: ErrVerifyMismatch,
+}
+
 const (
 	ARGON2_MIN_TIME = uint32(1)
 	ARGON2_MAX_TIME = uint32(4294967295)
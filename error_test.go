@@ -0,0 +1,140 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package argon2_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/matthewhartstonge/argon2"
+)
+
+// libargon2Messages are libargon2's argon2_error_message strings, indexed by
+// the negated error code.
+var libargon2Messages = []string{
+	"OK",
+	"Output pointer is NULL",
+	"Output is too short",
+	"Output is too long",
+	"Password is too short",
+	"Password is too long",
+	"Salt is too short",
+	"Salt is too long",
+	"Associated data is too short",
+	"Associated data is too long",
+	"Secret is too short",
+	"Secret is too long",
+	"Time cost is too small",
+	"Time cost is too large",
+	"Memory cost is too small",
+	"Memory cost is too large",
+	"Too few lanes",
+	"Too many lanes",
+	"Password pointer is NULL, but password length is not 0",
+	"Salt pointer is NULL, but salt length is not 0",
+	"Secret pointer is NULL, but secret length is not 0",
+	"Associated data pointer is NULL, but ad length is not 0",
+	"Memory allocation error",
+	"The free memory callback is NULL",
+	"The allocate memory callback is NULL",
+	"Argon2_Context context is NULL",
+	"There is no such version of Argon2",
+	"Output pointer mismatch",
+	"Not enough threads",
+	"Too many threads",
+	"Missing arguments",
+	"Encoding failed",
+	"Decoding failed",
+	"Threading failure",
+	"Some of encoded parameters are too long or too short",
+	"The password does not match the supplied hash",
+}
+
+func TestErrorCode(t *testing.T) {
+	for i := 1; i < len(libargon2Messages); i++ {
+		code := -i
+		err := argon2.ErrorFromCode(code)
+
+		var argonErr argon2.Error
+		if !errors.As(err, &argonErr) {
+			t.Fatalf("ErrorFromCode(%d) = %v, want an argon2.Error", code, err)
+		}
+		if got := argonErr.Code(); got != code {
+			t.Errorf("ErrorFromCode(%d).Code() = %d", code, got)
+		}
+		if !strings.EqualFold(err.Error(), libargon2Messages[i]) {
+			t.Errorf("ErrorFromCode(%d) = %q, want %q", code, err, libargon2Messages[i])
+		}
+	}
+}
+
+func TestErrorFromCode(t *testing.T) {
+	tests := []struct {
+		code int
+		want error
+	}{
+		{code: 0, want: nil},
+		{code: -1, want: argon2.ErrOutputPtrNull},
+		{code: -26, want: argon2.ErrIncorrectType},
+		{code: -35, want: argon2.ErrVerifyMismatch},
+	}
+	for _, tt := range tests {
+		if got := argon2.ErrorFromCode(tt.code); got != tt.want {
+			t.Errorf("ErrorFromCode(%d) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+
+	for _, code := range []int{1, -36} {
+		err := argon2.ErrorFromCode(code)
+		if err == nil || !strings.Contains(err.Error(), "unknown error code") {
+			t.Errorf("ErrorFromCode(%d) = %v, want an unknown error code", code, err)
+		}
+		if coder, ok := err.(interface{ Code() int }); !ok || coder.Code() != code {
+			t.Errorf("ErrorFromCode(%d).Code() != %d", code, code)
+		}
+	}
+}
+
+func TestErrorCodeWrapped(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", argon2.ErrDecodingFail)
+
+	var argonErr argon2.Error
+	if !errors.As(err, &argonErr) || argonErr.Code() != -32 {
+		t.Errorf("errors.As(%v) = %v", err, argonErr)
+	}
+	if !errors.Is(err, argon2.ErrorFromCode(-32)) {
+		t.Errorf("errors.Is(%v, ErrorFromCode(-32)) = false", err)
+	}
+}
+
+func TestErrorCodeUnknown(t *testing.T) {
+	tests := []struct {
+		err  argon2.Error
+		want int
+	}{
+		{err: argon2.ErrModeUnsupported, want: argon2.ErrIncorrectType.Code()},
+		{err: argon2.Error("custom"), want: argon2.ErrIncorrectParameter.Code()},
+		{err: argon2.Error(""), want: argon2.ErrIncorrectParameter.Code()},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Code(); got != tt.want {
+			t.Errorf("Error(%q).Code() = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
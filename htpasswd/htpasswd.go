@@ -0,0 +1,316 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package htpasswd reads and writes Apache htpasswd style password files
+// containing argon2 hashes, for example:
+//
+//	alice:$argon2id$v=19$m=65536,t=3,p=4$c29tZXNhbHQ$RdescudvJCsgt3ub+b+dWRWJTmaaJObG
+//
+// Entries hashed with other schemes are preserved when the file is saved,
+// but can't be verified.
+package htpasswd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/matthewhartstonge/argon2"
+)
+
+var (
+	// ErrInvalidUser is returned if a username is empty, contains a colon or
+	// line break, has leading or trailing whitespace, or starts with a '#',
+	// any of which would be lost when the file is read back.
+	ErrInvalidUser = errors.New("htpasswd: invalid username")
+	// ErrUserNotFound is returned if a user doesn't exist in the file.
+	ErrUserNotFound = errors.New("htpasswd: user not found")
+)
+
+// File is an htpasswd style password file. It is safe for concurrent use.
+type File struct {
+	path   string
+	config argon2.Config
+
+	mu      sync.RWMutex
+	users   map[string][]byte
+	order   []string
+	modTime time.Time
+	size    int64
+}
+
+// New returns an empty File which will be written to `path` on Save. New
+// passwords are hashed using `config`.
+func New(path string, config argon2.Config) *File {
+	return &File{
+		path:   path,
+		config: config,
+		users:  map[string][]byte{},
+	}
+}
+
+// Open reads the password file at `path`. New passwords are hashed using
+// `config`.
+func Open(path string, config argon2.Config) (*File, error) {
+	f := New(path, config)
+	if err := f.load(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// load (re)reads the file from disk, replacing the in-memory entries.
+func (f *File) load() error {
+	fh, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = fh.Close() }()
+
+	info, err := fh.Stat()
+	if err != nil {
+		return err
+	}
+
+	users, order, err := parse(fh)
+	if err != nil {
+		return fmt.Errorf("htpasswd: %s: %w", f.path, err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.users, f.order = users, order
+	f.modTime, f.size = info.ModTime(), info.Size()
+
+	return nil
+}
+
+// parse reads "user:hash" lines, skipping blank lines and comments.
+func parse(r io.Reader) (users map[string][]byte, order []string, err error) {
+	users = map[string][]byte{}
+
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := bytes.TrimSpace(s.Bytes())
+		if len(text) == 0 || text[0] == '#' {
+			continue
+		}
+
+		user, hash, ok := bytes.Cut(text, []byte(":"))
+		if !ok || len(user) == 0 || len(hash) == 0 {
+			return nil, nil, fmt.Errorf("line %d: malformed entry", line)
+		}
+
+		name := string(user)
+		if _, exists := users[name]; !exists {
+			order = append(order, name)
+		}
+		users[name] = append([]byte(nil), hash...)
+	}
+
+	return users, order, s.Err()
+}
+
+// Path returns the path of the password file.
+func (f *File) Path() string {
+	return f.path
+}
+
+// Users returns the usernames in the file, in file order.
+func (f *File) Users() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return append([]string(nil), f.order...)
+}
+
+// Lookup returns a copy of the encoded hash stored for `user`.
+func (f *File) Lookup(user string) ([]byte, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	hash, ok := f.users[user]
+	return append([]byte(nil), hash...), ok
+}
+
+// Verify returns true if `pwd` matches the hash stored for `user` and
+// otherwise false. ErrUserNotFound is returned for unknown users.
+func (f *File) Verify(user string, pwd []byte) (bool, error) {
+	hash, ok := f.Lookup(user)
+	if !ok {
+		return false, ErrUserNotFound
+	}
+	return argon2.VerifyEncoded(pwd, hash)
+}
+
+// Set adds `user`, or updates their password if they already exist. The
+// change is only persisted once Save is called.
+func (f *File) Set(user string, pwd []byte) error {
+	if !validUser(user) {
+		return ErrInvalidUser
+	}
+
+	hash, err := f.config.HashEncoded(pwd)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.users[user]; !exists {
+		f.order = append(f.order, user)
+	}
+	f.users[user] = hash
+
+	return nil
+}
+
+// validUser reports whether `user` survives being saved and parsed again.
+func validUser(user string) bool {
+	return user != "" &&
+		!strings.ContainsAny(user, ":\r\n") &&
+		strings.TrimSpace(user) == user &&
+		user[0] != '#'
+}
+
+// Delete removes `user`, returning ErrUserNotFound if they don't exist. The
+// change is only persisted once Save is called.
+func (f *File) Delete(user string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.users[user]; !exists {
+		return ErrUserNotFound
+	}
+
+	delete(f.users, user)
+	for i, name := range f.order {
+		if name == user {
+			f.order = append(f.order[:i], f.order[i+1:]...)
+			break
+		}
+	}
+
+	return nil
+}
+
+// Save atomically replaces the password file with the in-memory entries, by
+// writing them to a temporary file in the same directory and renaming it
+// over the original. Existing file permissions are preserved, new files are
+// created readable by the owner only.
+//
+// The lock is held until the recorded modification time is updated, so that
+// a concurrent Reload doesn't mistake the saved file for an external edit.
+func (f *File) Save() (err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var buf bytes.Buffer
+	for _, user := range f.order {
+		buf.WriteString(user)
+		buf.WriteByte(':')
+		buf.Write(f.users[user])
+		buf.WriteByte('\n')
+	}
+
+	perm := os.FileMode(0o600)
+	if info, err := os.Stat(f.path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), "."+filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), f.path); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+
+	f.modTime, f.size = info.ModTime(), info.Size()
+
+	return nil
+}
+
+// Reload re-reads the password file if it has changed on disk since it was
+// last read or saved, returning whether it was reloaded. Unsaved changes are
+// discarded on reload.
+func (f *File) Reload() (bool, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return false, err
+	}
+
+	f.mu.RLock()
+	changed := !info.ModTime().Equal(f.modTime) || info.Size() != f.size
+	f.mu.RUnlock()
+
+	if !changed {
+		return false, nil
+	}
+	return true, f.load()
+}
+
+// Watch calls Reload every `interval` until `ctx` is done, enabling hot
+// reloading of password files edited by other processes. Errors are passed
+// to `onError`, if provided, and the previously loaded entries are kept.
+func (f *File) Watch(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := f.Reload(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,252 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package htpasswd_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matthewhartstonge/argon2"
+	"github.com/matthewhartstonge/argon2/htpasswd"
+)
+
+var config = argon2.Config{
+	HashLength:  32,
+	SaltLength:  16,
+	TimeCost:    1,
+	MemoryCost:  1024,
+	Parallelism: 1,
+	Mode:        argon2.ModeArgon2id,
+	Version:     argon2.Version13,
+}
+
+const bcryptEntry = "bob:$2y$05$CCCCCCCCCCCCCCCCCCCCC.E5YPO9kmyuRGyh0XouQYb4YMJKvyOeW"
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".htpasswd")
+
+	f := htpasswd.New(path, config)
+	if err := f.Set("alice", []byte("s3cr3t")); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Set("carol", []byte("hunter2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("new file permissions = %v, want %v", perm, os.FileMode(0o600))
+	}
+
+	// Add an entry hashed with another scheme, which should be preserved.
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content = append(content, "\n# comment\n"+bcryptEntry+"\n"...)
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err = htpasswd.Open(path, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := f.Users(), []string{"alice", "carol", "bob"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Users() = %v, want %v", got, want)
+	}
+
+	ok, err := f.Verify("alice", []byte("s3cr3t"))
+	if err != nil || !ok {
+		t.Errorf("Verify() = %v, %v, want true, nil", ok, err)
+	}
+	ok, err = f.Verify("alice", []byte("hunter2"))
+	if err != nil || ok {
+		t.Errorf("Verify() with wrong password = %v, %v, want false, nil", ok, err)
+	}
+	if _, err := f.Verify("mallory", []byte("s3cr3t")); !errors.Is(err, htpasswd.ErrUserNotFound) {
+		t.Errorf("Verify() of unknown user error = %v, want %v", err, htpasswd.ErrUserNotFound)
+	}
+	if _, err := f.Verify("bob", []byte("U*U")); !errors.Is(err, argon2.ErrIncorrectType) {
+		t.Errorf("Verify() of non argon2 entry error = %v, want %v", err, argon2.ErrIncorrectType)
+	}
+
+	if err := f.Set("carol", []byte("correct horse")); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Delete("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Delete("alice"); !errors.Is(err, htpasswd.ErrUserNotFound) {
+		t.Errorf("Delete() of unknown user error = %v, want %v", err, htpasswd.ErrUserNotFound)
+	}
+	if err := f.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "carol:$argon2id$") || lines[1] != bcryptEntry {
+		t.Errorf("saved file = %q", content)
+	}
+
+	f, err = htpasswd.Open(path, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := f.Verify("carol", []byte("correct horse")); err != nil || !ok {
+		t.Errorf("Verify() of updated password = %v, %v, want true, nil", ok, err)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Save() should not leave temporary files behind, found %d files", len(entries))
+	}
+}
+
+func TestLookupCopy(t *testing.T) {
+	f := htpasswd.New(filepath.Join(t.TempDir(), ".htpasswd"), config)
+	if err := f.Set("alice", []byte("s3cr3t")); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, _ := f.Lookup("alice")
+	for i := range hash {
+		hash[i] = 0
+	}
+
+	if ok, err := f.Verify("alice", []byte("s3cr3t")); err != nil || !ok {
+		t.Errorf("Verify() after modifying Lookup() = %v, %v, want true, nil", ok, err)
+	}
+}
+
+func TestSetInvalidUser(t *testing.T) {
+	f := htpasswd.New(filepath.Join(t.TempDir(), ".htpasswd"), config)
+	for _, user := range []string{"", "al:ice", "al\nice", "al\rice", " alice", "alice\t", "#alice"} {
+		if err := f.Set(user, []byte("s3cr3t")); !errors.Is(err, htpasswd.ErrInvalidUser) {
+			t.Errorf("Set(%q) error = %v, want %v", user, err, htpasswd.ErrInvalidUser)
+		}
+	}
+}
+
+func TestSaveReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".htpasswd")
+	f := htpasswd.New(path, config)
+
+	users := []string{"alice", "b#b", "carol smith"}
+	for _, user := range users {
+		if err := f.Set(user, []byte(user+"-s3cr3t")); err != nil {
+			t.Fatalf("Set(%q) error = %v", user, err)
+		}
+	}
+	if err := f.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := htpasswd.Open(path, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reloaded.Users(); !reflect.DeepEqual(got, users) {
+		t.Fatalf("Users() = %q, want %q", got, users)
+	}
+	for _, user := range users {
+		if ok, err := reloaded.Verify(user, []byte(user+"-s3cr3t")); err != nil || !ok {
+			t.Errorf("Verify(%q) = %v, %v, want true, nil", user, ok, err)
+		}
+	}
+}
+
+func TestOpenMalformed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".htpasswd")
+	if err := os.WriteFile(path, []byte("alice\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := htpasswd.Open(path, config); err == nil {
+		t.Error("Open() of a malformed file should have returned an error")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".htpasswd")
+	if err := os.WriteFile(path, []byte(bcryptEntry+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := htpasswd.Open(path, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := f.Reload()
+	if err != nil || reloaded {
+		t.Errorf("Reload() of unchanged file = %v, %v, want false, nil", reloaded, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f.Watch(ctx, 10*time.Millisecond, func(err error) { t.Error(err) })
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	other := htpasswd.New(path, config)
+	if err := other.Set("alice", []byte("s3cr3t")); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, ok := f.Lookup("alice"); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Watch() did not reload the changed file")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, ok := f.Lookup("bob"); ok {
+		t.Error("reloaded file should no longer contain bob")
+	}
+}
@@ -0,0 +1,330 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package argon2
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Codec converts between a framework specific representation of an argon2
+// hash and a Raw struct.
+type Codec interface {
+	// Match reports whether `encoded` looks like this codec's
+	// representation.
+	Match(encoded []byte) bool
+
+	// Decode turns `encoded` back into a Raw struct.
+	Decode(encoded []byte) (Raw, error)
+
+	// Encode turns `raw` into this codec's representation. Only CodecPHC
+	// encodes wrapped hashes, others return ErrEncodingFail.
+	Encode(raw *Raw) ([]byte, error)
+}
+
+var (
+	// CodecPHC is the standard "$argon2id$v=19$m=...,t=...,p=...$salt$hash"
+	// representation implemented by Encode and Decode.
+	CodecPHC Codec = prefixCodec{}
+
+	// CodecSymfony handles hashes written by Symfony's native and sodium
+	// password hashers, which use PHP's password_hash and therefore the
+	// standard PHC representation.
+	CodecSymfony = CodecPHC
+
+	// CodecSpring handles hashes written by Spring Security's
+	// Argon2PasswordEncoder via a DelegatingPasswordEncoder, which prefixes
+	// the PHC representation with "{argon2}" (or "{argon2@SpringSecurity_v5_8}"
+	// when decoding). Hashes written by a bare Argon2PasswordEncoder use the
+	// standard representation, see CodecPHC.
+	CodecSpring Codec = springCodec{}
+
+	// CodecDjango handles hashes written by Django's Argon2PasswordHasher,
+	// which prefixes the PHC representation with "argon2", for example
+	// "argon2$argon2id$v=19$m=102400,t=2,p=8$salt$hash".
+	CodecDjango Codec = prefixCodec{prefix: "argon2"}
+
+	// CodecLDAP handles LDAP userPassword values using the OpenLDAP argon2
+	// password module's "{ARGON2}" scheme. Scheme names are matched case
+	// insensitively.
+	CodecLDAP Codec = prefixCodec{prefix: "{ARGON2}", fold: true}
+
+	// CodecKeycloak handles Keycloak argon2 password credentials, which store
+	// the salt and hash separately from the parameters. The representation
+	// is the JSON credential object, as found in realm exports, containing
+	// the "secretData" and "credentialData" JSON strings.
+	CodecKeycloak Codec = keycloakCodec{}
+)
+
+// codecs lists the Codecs used by DecodeAny, in the order they are matched.
+var codecs = []Codec{
+	CodecPHC,
+	CodecKeycloak,
+	CodecLDAP,
+	CodecSpring,
+	CodecDjango,
+}
+
+// matchCodec returns the first Codec matching `encoded`.
+func matchCodec(encoded []byte) (Codec, bool) {
+	for _, c := range codecs {
+		if c.Match(encoded) {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// DecodeAny works like Decode, but additionally understands the Django,
+// Spring Security, Keycloak and LDAP representations of argon2 hashes. See
+// the Codec variables.
+func DecodeAny(encoded []byte) (Raw, error) {
+	c, ok := matchCodec(encoded)
+	if !ok {
+		return Raw{}, ErrIncorrectType
+	}
+	return c.Decode(encoded)
+}
+
+// encodableWrap returns an error if `raw` wraps a legacy hash, which only the
+// PHC representation can record. Other systems would store a credential that
+// never verifies.
+func encodableWrap(raw *Raw) error {
+	if raw.Wrap != "" {
+		return fmt.Errorf("argon2: only the PHC representation can encode %s wrapped hashes: %w", raw.Wrap, ErrEncodingFail)
+	}
+	return nil
+}
+
+// prefixCodec handles representations which simply prefix the standard PHC
+// representation.
+type prefixCodec struct {
+	prefix string
+	// fold matches the prefix case-insensitively.
+	fold bool
+}
+
+// trim returns `encoded` without the codec's prefix, or nil if the prefix
+// doesn't match.
+func (c prefixCodec) trim(encoded []byte) []byte {
+	if len(encoded) < len(c.prefix) {
+		return nil
+	}
+
+	head := encoded[:len(c.prefix)]
+	if !bytes.Equal(head, []byte(c.prefix)) && !(c.fold && bytes.EqualFold(head, []byte(c.prefix))) {
+		return nil
+	}
+
+	return encoded[len(c.prefix):]
+}
+
+func (c prefixCodec) Match(encoded []byte) bool {
+	return bytes.HasPrefix(c.trim(encoded), decPrefix)
+}
+
+func (c prefixCodec) Decode(encoded []byte) (Raw, error) {
+	if !c.Match(encoded) {
+		return Raw{}, ErrIncorrectType
+	}
+	return Decode(c.trim(encoded))
+}
+
+func (c prefixCodec) Encode(raw *Raw) ([]byte, error) {
+	if raw.Config.Mode.String() == "unknown" {
+		return nil, ErrIncorrectType
+	}
+	if c.prefix != "" {
+		if err := encodableWrap(raw); err != nil {
+			return nil, err
+		}
+	}
+	return append([]byte(c.prefix), raw.Encode()...), nil
+}
+
+// springCodec handles Spring Security's DelegatingPasswordEncoder ids, which
+// may carry a version suffix, e.g. "{argon2@SpringSecurity_v5_8}".
+type springCodec struct{}
+
+// trim returns `encoded` without its "{argon2...}" id, or nil if there is no
+// such id.
+func (springCodec) trim(encoded []byte) []byte {
+	id, rest, ok := bytes.Cut(encoded, []byte("}"))
+	if !ok || !(bytes.Equal(id, []byte("{argon2")) || bytes.HasPrefix(id, []byte("{argon2@"))) {
+		return nil
+	}
+	return rest
+}
+
+func (c springCodec) Match(encoded []byte) bool {
+	return bytes.HasPrefix(c.trim(encoded), decPrefix)
+}
+
+func (c springCodec) Decode(encoded []byte) (Raw, error) {
+	if !c.Match(encoded) {
+		return Raw{}, ErrIncorrectType
+	}
+	return Decode(c.trim(encoded))
+}
+
+func (springCodec) Encode(raw *Raw) ([]byte, error) {
+	return prefixCodec{prefix: "{argon2}"}.Encode(raw)
+}
+
+// keycloakCredential is the credential representation found in Keycloak's
+// realm exports and admin API. Both data fields contain JSON documents
+// serialised as strings.
+type keycloakCredential struct {
+	Type           string `json:"type"`
+	SecretData     string `json:"secretData"`
+	CredentialData string `json:"credentialData"`
+}
+
+type keycloakSecretData struct {
+	Value                string              `json:"value"`
+	Salt                 string              `json:"salt"`
+	AdditionalParameters map[string][]string `json:"additionalParameters"`
+}
+
+type keycloakCredentialData struct {
+	HashIterations       uint32              `json:"hashIterations"`
+	Algorithm            string              `json:"algorithm"`
+	AdditionalParameters map[string][]string `json:"additionalParameters"`
+}
+
+// keycloakCodec handles Keycloak's argon2 password hash provider.
+type keycloakCodec struct{}
+
+func (keycloakCodec) Match(encoded []byte) bool {
+	encoded = bytes.TrimSpace(encoded)
+	return bytes.HasPrefix(encoded, []byte("{")) && bytes.Contains(encoded, []byte(`"credentialData"`))
+}
+
+func (keycloakCodec) Decode(encoded []byte) (Raw, error) {
+	var (
+		cred   keycloakCredential
+		secret keycloakSecretData
+		data   keycloakCredentialData
+	)
+	if json.Unmarshal(encoded, &cred) != nil ||
+		json.Unmarshal([]byte(cred.SecretData), &secret) != nil ||
+		json.Unmarshal([]byte(cred.CredentialData), &data) != nil {
+		return Raw{}, ErrDecodingFail
+	}
+	if data.Algorithm != "argon2" {
+		return Raw{}, ErrIncorrectType
+	}
+
+	param := func(key string) string {
+		if v := data.AdditionalParameters[key]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+
+	mode, err := ParseMode("argon2" + param("type"))
+	if err != nil {
+		return Raw{}, err
+	}
+	version, err := ParseVersion(param("version"))
+	if err != nil {
+		return Raw{}, err
+	}
+
+	m, me := strconv.ParseUint(param("memory"), 10, 32)
+	p, pe := strconv.ParseUint(param("parallelism"), 10, 8)
+	salt, se := base64.StdEncoding.DecodeString(secret.Salt)
+	hash, he := base64.StdEncoding.DecodeString(secret.Value)
+	if me != nil || pe != nil || se != nil || he != nil ||
+		m == 0 || p == 0 || data.HashIterations == 0 || len(salt) == 0 || len(hash) == 0 {
+		return Raw{}, ErrDecodingFail
+	}
+
+	raw := Raw{
+		Config: Config{
+			HashLength:  uint32(len(hash)),
+			SaltLength:  uint32(len(salt)),
+			TimeCost:    data.HashIterations,
+			MemoryCost:  uint32(m),
+			Parallelism: uint8(p),
+			Mode:        mode,
+			Version:     version,
+		},
+		Salt: salt,
+		Hash: hash,
+	}
+	// Keycloak computes version 1.0 hashes using version 1.0 of the
+	// algorithm.
+	if version == Version10 {
+		raw.Config.Options = &Options{StrictVersion10: true}
+	}
+	return raw, nil
+}
+
+func (keycloakCodec) Encode(raw *Raw) ([]byte, error) {
+	if err := encodableWrap(raw); err != nil {
+		return nil, err
+	}
+
+	c := raw.Config
+	typ, err := c.Mode.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	var version string
+	switch c.Version {
+	case Version10:
+		version = "1.0"
+	case Version13:
+		version = "1.3"
+	default:
+		return nil, ErrIncorrectType
+	}
+
+	secret, err := json.Marshal(keycloakSecretData{
+		Value:                base64.StdEncoding.EncodeToString(raw.Hash),
+		Salt:                 base64.StdEncoding.EncodeToString(raw.Salt),
+		AdditionalParameters: map[string][]string{},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(keycloakCredentialData{
+		HashIterations: c.TimeCost,
+		Algorithm:      "argon2",
+		AdditionalParameters: map[string][]string{
+			"hashLength":  {strconv.Itoa(len(raw.Hash))},
+			"memory":      {strconv.FormatUint(uint64(c.MemoryCost), 10)},
+			"type":        {string(bytes.ToLower(typ[len("argon2"):]))},
+			"version":     {version},
+			"parallelism": {strconv.FormatUint(uint64(c.Parallelism), 10)},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(keycloakCredential{
+		Type:           "password",
+		SecretData:     string(secret),
+		CredentialData: string(data),
+	})
+}
@@ -0,0 +1,226 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package argon2_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/matthewhartstonge/argon2"
+)
+
+const keycloakCredential = `{
+  "type": "password",
+  "createdDate": 1700000000000,
+  "secretData": "{\"value\":\"i3ZCXD8RMwu4akQl0xCL9L3ZJjV0lIutsAO27+vSS5s=\",\"salt\":\"c2FsdHNhbHQ=\",\"additionalParameters\":{}}",
+  "credentialData": "{\"hashIterations\":1,\"algorithm\":\"argon2\",\"additionalParameters\":{\"hashLength\":[\"32\"],\"memory\":[\"32768\"],\"type\":[\"id\"],\"version\":[\"1.3\"],\"parallelism\":[\"1\"]}}"
+}`
+
+func TestCodecs(t *testing.T) {
+	want, err := argon2.Decode(expectedEncoded)
+	mustBeFalsey(t, "err", err)
+
+	tests := []struct {
+		name    string
+		codec   argon2.Codec
+		encoded string
+		// written is set if the codec writes a different representation
+		// than the one decoded.
+		written string
+	}{
+		{
+			name:    "phc",
+			codec:   argon2.CodecPHC,
+			encoded: string(expectedEncoded),
+		},
+		{
+			name:    "symfony",
+			codec:   argon2.CodecSymfony,
+			encoded: string(expectedEncoded),
+		},
+		{
+			name:    "django",
+			codec:   argon2.CodecDjango,
+			encoded: "argon2" + string(expectedEncoded),
+		},
+		{
+			name:    "spring",
+			codec:   argon2.CodecSpring,
+			encoded: "{argon2}" + string(expectedEncoded),
+		},
+		{
+			name:    "spring versioned id",
+			codec:   argon2.CodecSpring,
+			encoded: "{argon2@SpringSecurity_v5_8}" + string(expectedEncoded),
+			written: "{argon2}" + string(expectedEncoded),
+		},
+		{
+			name:    "ldap",
+			codec:   argon2.CodecLDAP,
+			encoded: "{ARGON2}" + string(expectedEncoded),
+		},
+		{
+			name:    "ldap lower case scheme",
+			codec:   argon2.CodecLDAP,
+			encoded: "{argon2}" + string(expectedEncoded),
+			written: "{ARGON2}" + string(expectedEncoded),
+		},
+		{
+			name:    "keycloak",
+			codec:   argon2.CodecKeycloak,
+			encoded: keycloakCredential,
+			written: `{"type":"password","secretData":"{\"value\":\"i3ZCXD8RMwu4akQl0xCL9L3ZJjV0lIutsAO27+vSS5s=\",\"salt\":\"c2FsdHNhbHQ=\",\"additionalParameters\":{}}","credentialData":"{\"hashIterations\":1,\"algorithm\":\"argon2\",\"additionalParameters\":{\"hashLength\":[\"32\"],\"memory\":[\"32768\"],\"parallelism\":[\"1\"],\"type\":[\"id\"],\"version\":[\"1.3\"]}}"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.codec.Match([]byte(tt.encoded)) {
+				t.Fatalf("Match() should match %s", tt.encoded)
+			}
+
+			got, err := tt.codec.Decode([]byte(tt.encoded))
+			mustBeFalsey(t, "err", err)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Decode() = %+v, want %+v", got, want)
+			}
+
+			got, err = argon2.DecodeAny([]byte(tt.encoded))
+			mustBeFalsey(t, "err", err)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("DecodeAny() = %+v, want %+v", got, want)
+			}
+
+			written := tt.written
+			if written == "" {
+				written = tt.encoded
+			}
+			enc, err := tt.codec.Encode(&want)
+			mustBeFalsey(t, "err", err)
+			if string(enc) != written {
+				t.Errorf("Encode() = %s, want %s", enc, written)
+			}
+
+			ok, err := argon2.VerifyEncoded(password, []byte(tt.encoded))
+			mustBeFalsey(t, "err", err)
+			if !ok {
+				t.Error("VerifyEncoded() should match the password")
+			}
+		})
+	}
+}
+
+// TestKeycloakVersion10 verifies a Keycloak credential computed using
+// version 1.0 of the algorithm, as Keycloak does for version "1.0".
+func TestKeycloakVersion10(t *testing.T) {
+	credential := `{
+  "type": "password",
+  "secretData": "{\"value\":\"AJ8CYMqmjnw/XHeJXxfAgVhK4EEH8ex/iNHzAyVzbJg=\",\"salt\":\"c29tZXNhbHRzb21lc2FsdA==\",\"additionalParameters\":{}}",
+  "credentialData": "{\"hashIterations\":3,\"algorithm\":\"argon2\",\"additionalParameters\":{\"hashLength\":[\"32\"],\"memory\":[\"256\"],\"type\":[\"i\"],\"version\":[\"1.0\"],\"parallelism\":[\"4\"]}}"
+}`
+
+	raw, err := argon2.CodecKeycloak.Decode([]byte(credential))
+	mustBeFalsey(t, "err", err)
+
+	ok, err := raw.Verify(password)
+	if err != nil || !ok {
+		t.Errorf("Verify() = %v, %v, want true, nil", ok, err)
+	}
+}
+
+func TestCodecsEncodeWrapped(t *testing.T) {
+	legacy := []byte("5f4dcc3b5aa765d61d8327deb882cf99") // md5("password")
+	raw, err := config.HashWrapped(legacy, "md5")
+	mustBeFalsey(t, "err", err)
+
+	if _, err := argon2.CodecPHC.Encode(&raw); err != nil {
+		t.Errorf("CodecPHC.Encode() error = %v", err)
+	}
+
+	tests := map[string]argon2.Codec{
+		"django":   argon2.CodecDjango,
+		"ldap":     argon2.CodecLDAP,
+		"spring":   argon2.CodecSpring,
+		"keycloak": argon2.CodecKeycloak,
+	}
+	for name, codec := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := codec.Encode(&raw); !errors.Is(err, argon2.ErrEncodingFail) {
+				t.Errorf("Encode() error = %v, want %v", err, argon2.ErrEncodingFail)
+			}
+		})
+	}
+}
+
+func TestDecodeAnyError(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr error
+	}{
+		{
+			name:    "bcrypt",
+			input:   "$2y$10$B93GqMy3DNkIvyLbsxgtFOG2jwqvatQNUTeh3bPYvcCv9jiQgCO9S",
+			wantErr: argon2.ErrIncorrectType,
+		},
+		{
+			name:    "django pbkdf2",
+			input:   "pbkdf2_sha256$260000$c2FsdA$aGFzaA==",
+			wantErr: argon2.ErrIncorrectType,
+		},
+		{
+			name:    "ldap ssha",
+			input:   "{SSHA}aGFzaHNhbHQ=",
+			wantErr: argon2.ErrIncorrectType,
+		},
+		{
+			name:    "spring bcrypt",
+			input:   "{bcrypt}$2y$10$B93GqMy3DNkIvyLbsxgtFOG2jwqvatQNUTeh3bPYvcCv9jiQgCO9S",
+			wantErr: argon2.ErrIncorrectType,
+		},
+		{
+			name:    "django corrupt hash",
+			input:   "argon2$argon2id$v=19$m=32768,t=1,p=1$c2FsdHNhbHQ$",
+			wantErr: argon2.ErrDecodingFail,
+		},
+		{
+			name:    "keycloak pbkdf2",
+			input:   `{"type":"password","secretData":"{\"value\":\"aGFzaA==\",\"salt\":\"c2FsdA==\"}","credentialData":"{\"hashIterations\":27500,\"algorithm\":\"pbkdf2-sha256\"}"}`,
+			wantErr: argon2.ErrIncorrectType,
+		},
+		{
+			name:    "keycloak missing memory",
+			input:   `{"type":"password","secretData":"{\"value\":\"aGFzaA==\",\"salt\":\"c2FsdA==\"}","credentialData":"{\"hashIterations\":1,\"algorithm\":\"argon2\",\"additionalParameters\":{\"type\":[\"id\"],\"version\":[\"1.3\"],\"parallelism\":[\"1\"]}}"}`,
+			wantErr: argon2.ErrDecodingFail,
+		},
+		{
+			name:    "keycloak malformed secret data",
+			input:   `{"type":"password","secretData":"{","credentialData":"{}"}`,
+			wantErr: argon2.ErrDecodingFail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := argon2.DecodeAny([]byte(tt.input))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package kdbx
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/matthewhartstonge/argon2"
+)
+
+// Type is the type of a VariantDictionary value.
+type Type byte
+
+// VariantDictionary value types.
+const (
+	TypeNone      Type = 0x00
+	TypeUInt32    Type = 0x04
+	TypeUInt64    Type = 0x05
+	TypeBool      Type = 0x08
+	TypeInt32     Type = 0x0c
+	TypeInt64     Type = 0x0d
+	TypeString    Type = 0x18
+	TypeByteArray Type = 0x42
+)
+
+const (
+	// dictionaryVersion is the VariantDictionary format version written.
+	dictionaryVersion = 0x0100
+	// dictionaryCritical masks the version bits which must not be newer
+	// than dictionaryVersion to parse a VariantDictionary.
+	dictionaryCritical = 0xff00
+)
+
+// Dictionary is a KDBX VariantDictionary, which maps names to values of type
+// uint32, uint64, bool, int32, int64, string or []byte.
+type Dictionary map[string]any
+
+// ParseDictionary parses a binary VariantDictionary, as stored in the KDF
+// parameters of KDBX 4 headers.
+func ParseDictionary(data []byte) (Dictionary, error) {
+	if len(data) < 2 || binary.LittleEndian.Uint16(data)&dictionaryCritical > dictionaryVersion&dictionaryCritical {
+		return nil, argon2.ErrDecodingFail
+	}
+	data = data[2:]
+
+	d := Dictionary{}
+	for {
+		if len(data) == 0 {
+			return nil, argon2.ErrDecodingFail
+		}
+
+		typ := Type(data[0])
+		data = data[1:]
+		if typ == TypeNone {
+			break
+		}
+
+		name, rest, ok := readField(data)
+		if !ok || !utf8.Valid(name) {
+			return nil, argon2.ErrDecodingFail
+		}
+		value, rest, ok := readField(rest)
+		if !ok {
+			return nil, argon2.ErrDecodingFail
+		}
+		data = rest
+
+		v, err := parseValue(typ, value)
+		if err != nil {
+			return nil, err
+		}
+		d[string(name)] = v
+	}
+
+	if len(data) != 0 {
+		return nil, argon2.ErrDecodingFail
+	}
+
+	return d, nil
+}
+
+// readField reads an int32 length prefixed byte slice from data, returning
+// a copy of it and the remaining bytes.
+func readField(data []byte) (b, rest []byte, ok bool) {
+	if len(data) < 4 {
+		return nil, data, false
+	}
+
+	n := int32(binary.LittleEndian.Uint32(data))
+	data = data[4:]
+	if n < 0 || int64(n) > int64(len(data)) {
+		return nil, data, false
+	}
+
+	return append([]byte{}, data[:n]...), data[n:], true
+}
+
+// valueSizes are the sizes of the fixed size value types.
+var valueSizes = map[Type]int{
+	TypeUInt32: 4,
+	TypeUInt64: 8,
+	TypeBool:   1,
+	TypeInt32:  4,
+	TypeInt64:  8,
+}
+
+// parseValue decodes a value of type `typ`.
+func parseValue(typ Type, value []byte) (any, error) {
+	if n, ok := valueSizes[typ]; ok && len(value) != n {
+		return nil, argon2.ErrDecodingFail
+	}
+
+	switch typ {
+	case TypeUInt32:
+		return binary.LittleEndian.Uint32(value), nil
+	case TypeUInt64:
+		return binary.LittleEndian.Uint64(value), nil
+	case TypeBool:
+		return value[0] != 0, nil
+	case TypeInt32:
+		return int32(binary.LittleEndian.Uint32(value)), nil
+	case TypeInt64:
+		return int64(binary.LittleEndian.Uint64(value)), nil
+	case TypeString:
+		if !utf8.Valid(value) {
+			return nil, argon2.ErrDecodingFail
+		}
+		return string(value), nil
+	case TypeByteArray:
+		return value, nil
+	default:
+		return nil, argon2.ErrDecodingFail
+	}
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, returning the binary
+// VariantDictionary. Entries are written in name order.
+func (d Dictionary) MarshalBinary() ([]byte, error) {
+	names := make([]string, 0, len(d))
+	for name := range d {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := binary.LittleEndian.AppendUint16(nil, dictionaryVersion)
+	for _, name := range names {
+		var (
+			typ   Type
+			value []byte
+		)
+		switch v := d[name].(type) {
+		case uint32:
+			typ, value = TypeUInt32, binary.LittleEndian.AppendUint32(nil, v)
+		case uint64:
+			typ, value = TypeUInt64, binary.LittleEndian.AppendUint64(nil, v)
+		case bool:
+			typ, value = TypeBool, []byte{0}
+			if v {
+				value[0] = 1
+			}
+		case int32:
+			typ, value = TypeInt32, binary.LittleEndian.AppendUint32(nil, uint32(v))
+		case int64:
+			typ, value = TypeInt64, binary.LittleEndian.AppendUint64(nil, uint64(v))
+		case string:
+			typ, value = TypeString, []byte(v)
+		case []byte:
+			typ, value = TypeByteArray, v
+		default:
+			return nil, fmt.Errorf("kdbx: unsupported type %T of %q: %w", v, name, argon2.ErrEncodingFail)
+		}
+
+		if len(name) > math.MaxInt32 || len(value) > math.MaxInt32 {
+			return nil, argon2.ErrEncodingFail
+		}
+
+		buf = append(buf, byte(typ))
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(name)))
+		buf = append(buf, name...)
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(value)))
+		buf = append(buf, value...)
+	}
+
+	return append(buf, byte(TypeNone)), nil
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package kdbx_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/matthewhartstonge/argon2"
+	"github.com/matthewhartstonge/argon2/kdbx"
+)
+
+func TestDictionary(t *testing.T) {
+	want := kdbx.Dictionary{
+		"uint32": uint32(1),
+		"uint64": uint64(1 << 40),
+		"bool":   true,
+		"int32":  int32(-1),
+		"int64":  int64(-1 << 40),
+		"string": "value",
+		"bytes":  []byte{1, 2, 3},
+		"empty":  []byte{},
+	}
+
+	data, err := want.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := kdbx.ParseDictionary(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseDictionary(MarshalBinary()) = %v, want %v", got, want)
+	}
+}
+
+func TestDictionaryMarshalBinaryError(t *testing.T) {
+	d := kdbx.Dictionary{"float": 1.0}
+	if _, err := d.MarshalBinary(); !errors.Is(err, argon2.ErrEncodingFail) {
+		t.Errorf("MarshalBinary() error = %v, want %v", err, argon2.ErrEncodingFail)
+	}
+}
+
+func TestParseDictionaryError(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "empty", data: ""},
+		{name: "newer version", data: "000200"},
+		{name: "missing terminator", data: "0001"},
+		{name: "trailing data", data: "000100ff"},
+		{name: "unknown type", data: "0001ff01000000780000000000"},
+		{name: "negative name length", data: "000104ffffffff"},
+		{name: "truncated value", data: "000104010000007804000000010000"},
+		{name: "uint32 of the wrong size", data: "00010401000000780800000001000000000000000000"},
+		{name: "bool of the wrong size", data: "0001080100000078000000000000"},
+		{name: "invalid utf-8 string", data: "0001180100000078010000ff00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := kdbx.ParseDictionary(mustDecodeHex(t, tt.data)); !errors.Is(err, argon2.ErrDecodingFail) {
+				t.Errorf("ParseDictionary() error = %v, want %v", err, argon2.ErrDecodingFail)
+			}
+		})
+	}
+
+	// Minor version changes are compatible.
+	if _, err := kdbx.ParseDictionary(mustDecodeHex(t, "ff0100")); err != nil {
+		t.Errorf("ParseDictionary() error = %v, want nil", err)
+	}
+}
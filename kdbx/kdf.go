@@ -0,0 +1,263 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package kdbx implements the Argon2 key derivation function (KDF) of
+// KeePass KDBX 4 databases, whose parameters are stored as a VariantDictionary
+// in the database header.
+//
+// Refer: https://keepass.info/help/kb/kdbx_4.html
+package kdbx
+
+import (
+	"bytes"
+	"math"
+
+	"github.com/matthewhartstonge/argon2"
+)
+
+// KDF UUIDs, stored under the "$UUID" parameter.
+var (
+	Argon2dUUID  = [16]byte{0xef, 0x63, 0x6d, 0xdf, 0x8c, 0x29, 0x44, 0x4b, 0x91, 0xf7, 0xa9, 0xa4, 0x03, 0xe3, 0x0a, 0x0c}
+	Argon2idUUID = [16]byte{0x9e, 0x29, 0x8b, 0x19, 0x56, 0xdb, 0x47, 0x73, 0xb2, 0x3d, 0xfc, 0x3e, 0xc6, 0xf0, 0xa1, 0xe6}
+)
+
+// KDF parameter names.
+const (
+	ParamUUID        = "$UUID"
+	ParamSalt        = "S"
+	ParamParallelism = "P"
+	ParamMemory      = "M"
+	ParamIterations  = "I"
+	ParamVersion     = "V"
+	ParamSecretKey   = "K"
+	ParamAssocData   = "A"
+)
+
+// transformedKeyBytes is the length of the transformed key.
+const transformedKeyBytes = 32
+
+// Params are the parameters of the Argon2 KDF.
+type Params struct {
+	// Mode is either argon2.ModeArgon2d or argon2.ModeArgon2id, stored as
+	// the KDF UUID.
+	Mode argon2.Mode
+	// Salt is stored as "S".
+	Salt []byte
+	// Parallelism is the number of lanes, stored as "P".
+	Parallelism uint32
+	// Memory is the memory size in bytes, stored as "M".
+	Memory uint64
+	// Iterations is the number of passes, stored as "I".
+	Iterations uint64
+	// Version is stored as "V".
+	Version argon2.Version
+	// SecretKey is an optional secret, stored as "K".
+	SecretKey []byte
+	// AssocData is optional associated data, stored as "A".
+	AssocData []byte
+}
+
+// New returns the Params of `c`, which must use argon2d or argon2id, with a
+// new salt. Any secret or associated data set on c.Options is included.
+func New(c argon2.Config) (Params, error) {
+	switch {
+	case c.Mode != argon2.ModeArgon2d && c.Mode != argon2.ModeArgon2id:
+		return Params{}, argon2.ErrIncorrectType
+	case c.SaltLength == 0:
+		return Params{}, argon2.ErrSaltTooShort
+	}
+
+	salt, err := c.NewSalt()
+	if err != nil {
+		return Params{}, err
+	}
+
+	p := Params{
+		Mode:        c.Mode,
+		Salt:        salt,
+		Parallelism: uint32(c.Parallelism),
+		Memory:      uint64(c.MemoryCost) * 1024,
+		Iterations:  uint64(c.TimeCost),
+		Version:     c.Version,
+	}
+	if c.Options != nil {
+		p.SecretKey = c.Options.Secret
+		p.AssocData = c.Options.AssociatedData
+	}
+
+	return p, nil
+}
+
+// Parse parses the binary KDF parameters VariantDictionary of a KDBX 4
+// header. Other KDFs, such as AES-KDF, return argon2.ErrIncorrectType.
+func Parse(data []byte) (Params, error) {
+	d, err := ParseDictionary(data)
+	if err != nil {
+		return Params{}, err
+	}
+	return FromDictionary(d)
+}
+
+// FromDictionary returns the Params stored in `d`.
+func FromDictionary(d Dictionary) (Params, error) {
+	uuid, ok := d[ParamUUID].([]byte)
+	if !ok {
+		return Params{}, argon2.ErrMissingArgs
+	}
+
+	var p Params
+	switch {
+	case bytes.Equal(uuid, Argon2dUUID[:]):
+		p.Mode = argon2.ModeArgon2d
+	case bytes.Equal(uuid, Argon2idUUID[:]):
+		p.Mode = argon2.ModeArgon2id
+	default:
+		return Params{}, argon2.ErrIncorrectType
+	}
+
+	var version uint32
+	if !lookup(d, ParamSalt, &p.Salt) ||
+		!lookup(d, ParamParallelism, &p.Parallelism) ||
+		!lookup(d, ParamMemory, &p.Memory) ||
+		!lookup(d, ParamIterations, &p.Iterations) ||
+		!lookup(d, ParamVersion, &version) {
+		return Params{}, argon2.ErrMissingArgs
+	}
+	p.Version = argon2.Version(version)
+
+	// The secret key and associated data are optional, but must be byte
+	// arrays if present.
+	if _, ok := d[ParamSecretKey]; ok && !lookup(d, ParamSecretKey, &p.SecretKey) {
+		return Params{}, argon2.ErrDecodingFail
+	}
+	if _, ok := d[ParamAssocData]; ok && !lookup(d, ParamAssocData, &p.AssocData) {
+		return Params{}, argon2.ErrDecodingFail
+	}
+
+	return p, nil
+}
+
+// lookup sets `v` to the value of `name` in `d`, reporting whether it's
+// present with the type of `v`.
+func lookup[T any](d Dictionary, name string, v *T) bool {
+	value, ok := d[name].(T)
+	if ok {
+		*v = value
+	}
+	return ok
+}
+
+// Dictionary returns the VariantDictionary storing `p`.
+func (p Params) Dictionary() (Dictionary, error) {
+	var uuid [16]byte
+	switch p.Mode {
+	case argon2.ModeArgon2d:
+		uuid = Argon2dUUID
+	case argon2.ModeArgon2id:
+		uuid = Argon2idUUID
+	default:
+		return nil, argon2.ErrIncorrectType
+	}
+
+	d := Dictionary{
+		ParamUUID:        uuid[:],
+		ParamSalt:        p.Salt,
+		ParamParallelism: p.Parallelism,
+		ParamMemory:      p.Memory,
+		ParamIterations:  p.Iterations,
+		ParamVersion:     uint32(p.Version),
+	}
+	if len(p.SecretKey) > 0 {
+		d[ParamSecretKey] = p.SecretKey
+	}
+	if len(p.AssocData) > 0 {
+		d[ParamAssocData] = p.AssocData
+	}
+
+	return d, nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, returning the binary
+// VariantDictionary storing `p`.
+func (p Params) MarshalBinary() ([]byte, error) {
+	d, err := p.Dictionary()
+	if err != nil {
+		return nil, err
+	}
+	return d.MarshalBinary()
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. See Parse.
+func (p *Params) UnmarshalBinary(data []byte) error {
+	parsed, err := Parse(data)
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+// Config returns the argon2.Config computing the transformed key. As in
+// KeePass, Memory is rounded down to whole kibibytes.
+func (p Params) Config() (argon2.Config, error) {
+	switch {
+	case p.Mode != argon2.ModeArgon2d && p.Mode != argon2.ModeArgon2id:
+		return argon2.Config{}, argon2.ErrIncorrectType
+	case len(p.Salt) == 0:
+		return argon2.Config{}, argon2.ErrSaltTooShort
+	case p.Iterations > math.MaxUint32:
+		return argon2.Config{}, argon2.ErrTimeTooLarge
+	case p.Memory/1024 > math.MaxUint32:
+		return argon2.Config{}, argon2.ErrMemoryTooMuch
+	case p.Parallelism > math.MaxUint8:
+		return argon2.Config{}, argon2.ErrLanesTooMany
+	}
+
+	c := argon2.Config{
+		HashLength:  transformedKeyBytes,
+		SaltLength:  uint32(len(p.Salt)),
+		TimeCost:    uint32(p.Iterations),
+		MemoryCost:  uint32(p.Memory / 1024),
+		Parallelism: uint8(p.Parallelism),
+		Mode:        p.Mode,
+		Version:     p.Version,
+	}
+	// KeePass computes version 1.0 keys using version 1.0 of the algorithm.
+	if len(p.SecretKey) > 0 || len(p.AssocData) > 0 || p.Version == argon2.Version10 {
+		c.Options = &argon2.Options{
+			Secret:          p.SecretKey,
+			AssociatedData:  p.AssocData,
+			StrictVersion10: p.Version == argon2.Version10,
+		}
+	}
+
+	return c, nil
+}
+
+// TransformKey derives the 32 byte transformed key from the composite key,
+// the SHA-256 hash of the concatenated hashes of the password, key file and
+// any other key components.
+func (p Params) TransformKey(compositeKey []byte) ([]byte, error) {
+	c, err := p.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := c.Hash(compositeKey, p.Salt)
+	if err != nil {
+		return nil, err
+	}
+	return r.Hash, nil
+}
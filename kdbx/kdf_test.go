@@ -0,0 +1,292 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package kdbx_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/matthewhartstonge/argon2"
+	"github.com/matthewhartstonge/argon2/kdbx"
+)
+
+// argon2dParams are argon2d KDF parameters with a secret key and associated
+// data, in the order KeePass writes them.
+var argon2dParams = strings.Join([]string{
+	"0001",
+	"4205000000245555494410000000ef636ddf8c29444b91f7a9a403e30a0c",
+	"050100000049080000000200000000000000",
+	"05010000004d080000000000100000000000",
+	"0401000000500400000002000000",
+	"0401000000560400000013000000",
+	"42010000005320000000000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+	"42010000004b0a000000736563726574206b6579",
+	"4201000000410a0000006173736f632064617461",
+	"00",
+}, "")
+
+func kdfSalt() []byte {
+	salt := make([]byte, 32)
+	for i := range salt {
+		salt[i] = byte(i)
+	}
+	return salt
+}
+
+func TestParse(t *testing.T) {
+	p, err := kdbx.Parse(mustDecodeHex(t, argon2dParams))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := kdbx.Params{
+		Mode:        argon2.ModeArgon2d,
+		Salt:        kdfSalt(),
+		Parallelism: 2,
+		Memory:      1 << 20,
+		Iterations:  2,
+		Version:     argon2.Version13,
+		SecretKey:   []byte("secret key"),
+		AssocData:   []byte("assoc data"),
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("Parse() = %+v, want %+v", p, want)
+	}
+
+	// Parsing what's written gives the same parameters, although the
+	// entries are written in a different order.
+	data, err := p.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p2 kdbx.Params
+	if err := p2.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p2, want) {
+		t.Errorf("UnmarshalBinary(MarshalBinary()) = %+v, want %+v", p2, want)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	aesKDF := kdbx.Dictionary{
+		kdbx.ParamUUID: mustDecodeHex(t, "c9d9f39a628a4460bf740d08c18a4fea"),
+		"R":            uint64(60000),
+		"S":            kdfSalt(),
+	}
+	noSalt := kdbx.Dictionary{
+		kdbx.ParamUUID:        kdbx.Argon2idUUID[:],
+		kdbx.ParamParallelism: uint32(2),
+		kdbx.ParamMemory:      uint64(1 << 20),
+		kdbx.ParamIterations:  uint64(2),
+		kdbx.ParamVersion:     uint32(argon2.Version13),
+	}
+	wrongType := kdbx.Dictionary{
+		kdbx.ParamUUID:        kdbx.Argon2idUUID[:],
+		kdbx.ParamSalt:        kdfSalt(),
+		kdbx.ParamParallelism: uint64(2),
+		kdbx.ParamMemory:      uint64(1 << 20),
+		kdbx.ParamIterations:  uint64(2),
+		kdbx.ParamVersion:     uint32(argon2.Version13),
+	}
+	secretString := kdbx.Dictionary{
+		kdbx.ParamUUID:        kdbx.Argon2idUUID[:],
+		kdbx.ParamSalt:        kdfSalt(),
+		kdbx.ParamParallelism: uint32(2),
+		kdbx.ParamMemory:      uint64(1 << 20),
+		kdbx.ParamIterations:  uint64(2),
+		kdbx.ParamVersion:     uint32(argon2.Version13),
+		kdbx.ParamSecretKey:   "secret key",
+	}
+
+	tests := []struct {
+		name    string
+		d       kdbx.Dictionary
+		wantErr error
+	}{
+		{name: "empty", d: kdbx.Dictionary{}, wantErr: argon2.ErrMissingArgs},
+		{name: "AES-KDF", d: aesKDF, wantErr: argon2.ErrIncorrectType},
+		{name: "no salt", d: noSalt, wantErr: argon2.ErrMissingArgs},
+		{name: "parallelism of the wrong type", d: wrongType, wantErr: argon2.ErrMissingArgs},
+		{name: "secret key of the wrong type", d: secretString, wantErr: argon2.ErrDecodingFail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.d.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := kdbx.Parse(data); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Parse() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestTransformKey checks transformed keys of the composite key of the
+// password "password" against the reference implementation.
+func TestTransformKey(t *testing.T) {
+	passwordHash := sha256.Sum256([]byte("password"))
+	compositeKey := sha256.Sum256(passwordHash[:])
+
+	tests := []struct {
+		name   string
+		mutate func(p *kdbx.Params)
+		want   string
+	}{
+		{
+			name:   "argon2d with secret key and associated data",
+			mutate: func(p *kdbx.Params) {},
+			want:   "c9ca40080dc6e124051ce30339cd1918dc0a93c809a3ea65d0f2510979907ce2",
+		},
+		{
+			name: "argon2id",
+			mutate: func(p *kdbx.Params) {
+				p.Mode = argon2.ModeArgon2id
+				p.SecretKey, p.AssocData = nil, nil
+			},
+			want: "fcd84c0f166eadb05cccd6a41e8347dc699c1682e0fb857d0779c03c5765b9c2",
+		},
+		{
+			name: "argon2d version 1.0",
+			mutate: func(p *kdbx.Params) {
+				p.Version = argon2.Version10
+				p.SecretKey, p.AssocData = nil, nil
+			},
+			want: "cb68ab912880133ae903eb7a267b75071fad682a202979995b66e93027235048",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := kdbx.Parse(mustDecodeHex(t, argon2dParams))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.mutate(&p)
+
+			key, err := p.TransformKey(compositeKey[:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hex.EncodeToString(key); got != tt.want {
+				t.Errorf("TransformKey() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigError(t *testing.T) {
+	tests := []struct {
+		name    string
+		p       kdbx.Params
+		wantErr error
+	}{
+		{
+			name:    "argon2i",
+			p:       kdbx.Params{Mode: argon2.ModeArgon2i, Salt: kdfSalt()},
+			wantErr: argon2.ErrIncorrectType,
+		},
+		{
+			name:    "no salt",
+			p:       kdbx.Params{Mode: argon2.ModeArgon2id},
+			wantErr: argon2.ErrSaltTooShort,
+		},
+		{
+			name:    "too many iterations",
+			p:       kdbx.Params{Mode: argon2.ModeArgon2id, Salt: kdfSalt(), Iterations: 1 << 32},
+			wantErr: argon2.ErrTimeTooLarge,
+		},
+		{
+			name:    "too much memory",
+			p:       kdbx.Params{Mode: argon2.ModeArgon2id, Salt: kdfSalt(), Memory: 1 << 42},
+			wantErr: argon2.ErrMemoryTooMuch,
+		},
+		{
+			name:    "too many lanes",
+			p:       kdbx.Params{Mode: argon2.ModeArgon2id, Salt: kdfSalt(), Parallelism: 256},
+			wantErr: argon2.ErrLanesTooMany,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.p.Config(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Config() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestNew creates argon2dParams from its salt, checking the transformed key
+// against TestTransformKey's.
+func TestNew(t *testing.T) {
+	want, err := kdbx.Parse(mustDecodeHex(t, argon2dParams))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := argon2.RecommendedDefaults()
+	c.Mode = argon2.ModeArgon2d
+	c.TimeCost = 2
+	c.MemoryCost = 1024
+	c.Parallelism = 2
+	c.SaltLength = 32
+	c.Options = &argon2.Options{
+		Rand:           bytes.NewReader(kdfSalt()),
+		Secret:         []byte("secret key"),
+		AssociatedData: []byte("assoc data"),
+	}
+
+	p, err := kdbx.New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("New() = %+v, want %+v", p, want)
+	}
+
+	passwordHash := sha256.Sum256([]byte("password"))
+	compositeKey := sha256.Sum256(passwordHash[:])
+	key, err := p.TransformKey(compositeKey[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := hex.EncodeToString(key), "c9ca40080dc6e124051ce30339cd1918dc0a93c809a3ea65d0f2510979907ce2"; got != want {
+		t.Errorf("TransformKey() = %s, want %s", got, want)
+	}
+
+	c.Mode = argon2.ModeArgon2i
+	if _, err := kdbx.New(c); !errors.Is(err, argon2.ErrIncorrectType) {
+		t.Errorf("New() error = %v, want %v", err, argon2.ErrIncorrectType)
+	}
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,277 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package argon2
+
+import (
+	"bytes"
+	"crypto/sha1" //nolint:gosec // required to verify legacy pbkdf2-sha1 hashes.
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Verifier verifies passwords against hashes produced by a password hashing
+// scheme other than argon2, enabling migrations via Config.VerifyAny.
+type Verifier interface {
+	// Verify returns true if `pwd` matches the hash in `encoded` and
+	// otherwise false. An error is returned if `encoded` is malformed.
+	Verify(pwd, encoded []byte) (bool, error)
+}
+
+// VerifierFunc is an adapter to allow the use of ordinary functions as a
+// Verifier.
+type VerifierFunc func(pwd, encoded []byte) (bool, error)
+
+// Verify calls f(pwd, encoded).
+func (f VerifierFunc) Verify(pwd, encoded []byte) (bool, error) {
+	return f(pwd, encoded)
+}
+
+var (
+	verifiersMu sync.RWMutex
+	verifiers   = map[string]Verifier{
+		"$2a$":            VerifierFunc(verifyBcrypt),
+		"$2b$":            VerifierFunc(verifyBcrypt),
+		"$2y$":            VerifierFunc(verifyBcrypt),
+		"$scrypt$":        VerifierFunc(verifyScrypt),
+		"$pbkdf2$":        pbkdf2Verifier(sha1.New),
+		"$pbkdf2-sha256$": pbkdf2Verifier(sha256.New),
+		"$pbkdf2-sha512$": pbkdf2Verifier(sha512.New),
+	}
+)
+
+// RegisterVerifier makes a Verifier available to Config.VerifyAny for
+// hashes starting with `prefix`. If several prefixes match an encoded hash,
+// the longest one wins. Registering a Verifier for an existing prefix
+// replaces it, while a nil Verifier removes it.
+//
+// Out of the box bcrypt ("$2a$", "$2b$", "$2y$") as well as the passlib
+// modular crypt formats for scrypt ("$scrypt$") and PBKDF2 ("$pbkdf2$",
+// "$pbkdf2-sha256$", "$pbkdf2-sha512$") are supported. Their parameters are
+// bounded to at most 1 GiB of memory and p=16 for scrypt, 10 million rounds
+// for PBKDF2, and 64 byte keys, with ErrDecodingFail returned above them.
+func RegisterVerifier(prefix string, v Verifier) {
+	verifiersMu.Lock()
+	defer verifiersMu.Unlock()
+
+	if v == nil {
+		delete(verifiers, prefix)
+		return
+	}
+	verifiers[prefix] = v
+}
+
+// lookupVerifier returns the registered Verifier with the longest prefix
+// matching `encoded`.
+func lookupVerifier(encoded []byte) (Verifier, bool) {
+	verifiersMu.RLock()
+	defer verifiersMu.RUnlock()
+
+	var (
+		match string
+		found Verifier
+	)
+	for prefix, v := range verifiers {
+		if len(prefix) > len(match) && bytes.HasPrefix(encoded, []byte(prefix)) {
+			match, found = prefix, v
+		}
+	}
+
+	return found, found != nil
+}
+
+// VerifyAny returns true if `pwd` matches `encoded`, which may either be an
+// encoded argon2 hash in any of the representations understood by
+// DecodeAny, or a hash supported by one of the registered Verifiers. See
+// RegisterVerifier.
+//
+// If the password matches, rehash reports whether the hash should be
+// migrated by rehashing `pwd` with `c`, either because it isn't an argon2
+// hash, or because it was generated with different parameters.
+func (c *Config) VerifyAny(pwd, encoded []byte) (ok, rehash bool, err error) {
+	if codec, found := matchCodec(encoded); found {
+		r, err := codec.Decode(encoded)
+		if err != nil {
+			return false, false, err
+		}
+
+		ok, err = r.Verify(pwd)
+		if err != nil || !ok {
+			return false, false, err
+		}
+
+		return true, c.needsRehash(&r), nil
+	}
+
+	v, found := lookupVerifier(encoded)
+	if !found {
+		return false, false, ErrIncorrectType
+	}
+
+	ok, err = v.Verify(pwd, encoded)
+	if err != nil || !ok {
+		return false, false, err
+	}
+
+	return true, true, nil
+}
+
+// needsRehash returns true if `raw` wraps a legacy hash, or was generated
+// with parameters that differ from `c`.
+func (c *Config) needsRehash(raw *Raw) bool {
+	rc := raw.Config
+	return raw.Wrap != "" ||
+		rc.Mode != c.Mode ||
+		rc.Version != c.Version ||
+		rc.MemoryCost != c.MemoryCost ||
+		rc.TimeCost != c.TimeCost ||
+		rc.Parallelism != c.Parallelism ||
+		uint32(len(raw.Hash)) != c.HashLength ||
+		uint32(len(raw.Salt)) != c.SaltLength
+}
+
+// Bounds on the parameters of legacy hashes, which are taken from the stored
+// hash and would otherwise let a crafted one exhaust memory or CPU time.
+const (
+	// maxScryptMemory bounds the 128*N*r bytes of memory used by scrypt.
+	maxScryptMemory = 1 << 30
+	// maxScryptParallelism bounds scrypt's p, which multiplies its work.
+	maxScryptParallelism = 16
+	// maxPBKDF2Rounds is several times the rounds recommended by OWASP.
+	maxPBKDF2Rounds = 10_000_000
+	// maxLegacyKeyLength bounds the derived key length, which multiplies
+	// the work of PBKDF2, to the largest digest supported.
+	maxLegacyKeyLength = sha512.Size
+)
+
+// errLegacyLimit is returned for legacy hashes with parameters above the
+// bounds above.
+var errLegacyLimit = fmt.Errorf("argon2: legacy hash parameters exceed the supported limits: %w", ErrDecodingFail)
+
+// verifyBcrypt verifies bcrypt hashes via x/crypto/bcrypt.
+func verifyBcrypt(pwd, encoded []byte) (bool, error) {
+	err := bcrypt.CompareHashAndPassword(encoded, pwd)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+		return false, nil
+	default:
+		return false, ErrDecodingFail
+	}
+}
+
+// verifyScrypt verifies passlib's "$scrypt$ln=<log2 N>,r=<r>,p=<p>$salt$hash"
+// format via x/crypto/scrypt.
+func verifyScrypt(pwd, encoded []byte) (bool, error) {
+	fields := bytes.Split(encoded, []byte("$"))
+	if len(fields) != 5 {
+		return false, ErrDecodingFail
+	}
+
+	var ln, r, p int
+	for _, param := range bytes.Split(fields[2], []byte(",")) {
+		k, v, _ := bytes.Cut(param, []byte("="))
+		n, err := strconv.Atoi(string(v))
+		if err != nil || n <= 0 {
+			return false, ErrDecodingFail
+		}
+
+		switch string(k) {
+		case "ln":
+			ln = n
+		case "r":
+			r = n
+		case "p":
+			p = n
+		default:
+			return false, ErrDecodingFail
+		}
+	}
+	if ln == 0 || ln > 30 || r == 0 || p == 0 {
+		return false, ErrDecodingFail
+	}
+	if uint64(r) > maxScryptMemory/(128<<ln) || p > maxScryptParallelism {
+		return false, errLegacyLimit
+	}
+
+	salt, sum, err := decodeAdaptedBase64(fields[3], fields[4])
+	if err != nil {
+		return false, err
+	}
+	if len(sum) > maxLegacyKeyLength {
+		return false, errLegacyLimit
+	}
+
+	key, err := scrypt.Key(pwd, salt, 1<<ln, r, p, len(sum))
+	if err != nil {
+		return false, ErrDecodingFail
+	}
+
+	return subtle.ConstantTimeCompare(key, sum) == 1, nil
+}
+
+// pbkdf2Verifier returns a Verifier for passlib's
+// "$pbkdf2[-<digest>]$<rounds>$salt$hash" formats.
+func pbkdf2Verifier(h func() hash.Hash) Verifier {
+	return VerifierFunc(func(pwd, encoded []byte) (bool, error) {
+		fields := bytes.Split(encoded, []byte("$"))
+		if len(fields) != 5 {
+			return false, ErrDecodingFail
+		}
+
+		rounds, err := strconv.Atoi(string(fields[2]))
+		if err != nil || rounds <= 0 {
+			return false, ErrDecodingFail
+		}
+		if rounds > maxPBKDF2Rounds {
+			return false, errLegacyLimit
+		}
+
+		salt, sum, err := decodeAdaptedBase64(fields[3], fields[4])
+		if err != nil {
+			return false, err
+		}
+		if len(sum) > maxLegacyKeyLength {
+			return false, errLegacyLimit
+		}
+
+		key := pbkdf2.Key(pwd, salt, rounds, len(sum), h)
+		return subtle.ConstantTimeCompare(key, sum) == 1, nil
+	})
+}
+
+// decodeAdaptedBase64 decodes a salt and hash pair encoded using passlib's
+// adapted base64, which swaps "+" for ".".
+func decodeAdaptedBase64(s, h []byte) (salt, sum []byte, err error) {
+	salt, se := enc64.DecodeString(string(bytes.ReplaceAll(s, []byte("."), []byte("+"))))
+	sum, he := enc64.DecodeString(string(bytes.ReplaceAll(h, []byte("."), []byte("+"))))
+	if se != nil || he != nil || len(salt) == 0 || len(sum) == 0 {
+		return nil, nil, ErrDecodingFail
+	}
+
+	return salt, sum, nil
+}
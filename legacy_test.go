@@ -0,0 +1,214 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package argon2_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/matthewhartstonge/argon2"
+)
+
+func TestVerifyAny(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword(password, bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := config.HashEncoded(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrapped, err := config.HashWrapped([]byte("5f4dcc3b5aa765d61d8327deb882cf99"), "md5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stale := config
+	stale.TimeCost++
+	staleHash, err := stale.HashEncoded(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		encoded    []byte
+		pwd        []byte
+		wantOk     bool
+		wantRehash bool
+		wantErr    error
+	}{
+		{
+			name:    "argon2 with current config",
+			encoded: current,
+			pwd:     password,
+			wantOk:  true,
+		},
+		{
+			name:       "argon2 with stale config",
+			encoded:    staleHash,
+			pwd:        password,
+			wantOk:     true,
+			wantRehash: true,
+		},
+		{
+			name:       "argon2 wrapping a legacy hash",
+			encoded:    wrapped.Encode(),
+			pwd:        password,
+			wantOk:     true,
+			wantRehash: true,
+		},
+		{
+			name:    "argon2 mismatch",
+			encoded: expectedEncoded,
+			pwd:     []byte("wrong"),
+		},
+		{
+			name:       "bcrypt",
+			encoded:    bcryptHash,
+			pwd:        password,
+			wantOk:     true,
+			wantRehash: true,
+		},
+		{
+			name:    "bcrypt mismatch",
+			encoded: bcryptHash,
+			pwd:     []byte("wrong"),
+		},
+		{
+			name:       "scrypt",
+			encoded:    []byte("$scrypt$ln=10,r=8,p=1$c2FsdHNhbHRzYWx0c2FsdA$BVMRKqdiVYikKAaPR1wucsKUKvw4TuPLkdEYtoSHas4"),
+			pwd:        password,
+			wantOk:     true,
+			wantRehash: true,
+		},
+		{
+			name:    "scrypt mismatch",
+			encoded: []byte("$scrypt$ln=10,r=8,p=1$c2FsdHNhbHRzYWx0c2FsdA$BVMRKqdiVYikKAaPR1wucsKUKvw4TuPLkdEYtoSHas4"),
+			pwd:     []byte("wrong"),
+		},
+		{
+			name:    "scrypt invalid params",
+			encoded: []byte("$scrypt$ln=10,r=8,q=1$c2FsdHNhbHRzYWx0c2FsdA$BVMRKqdiVYikKAaPR1wucsKUKvw4TuPLkdEYtoSHas4"),
+			pwd:     password,
+			wantErr: argon2.ErrDecodingFail,
+		},
+		{
+			name:    "scrypt memory too large",
+			encoded: []byte("$scrypt$ln=20,r=16,p=1$c2FsdHNhbHRzYWx0c2FsdA$BVMRKqdiVYikKAaPR1wucsKUKvw4TuPLkdEYtoSHas4"),
+			pwd:     password,
+			wantErr: argon2.ErrDecodingFail,
+		},
+		{
+			name:    "scrypt parallelism too large",
+			encoded: []byte("$scrypt$ln=10,r=8,p=1000000$c2FsdHNhbHRzYWx0c2FsdA$BVMRKqdiVYikKAaPR1wucsKUKvw4TuPLkdEYtoSHas4"),
+			pwd:     password,
+			wantErr: argon2.ErrDecodingFail,
+		},
+		{
+			name:       "pbkdf2-sha1",
+			encoded:    []byte("$pbkdf2$29000$c2FsdHNhbHRzYWx0c2FsdA$bAB4z61rfX1KUSAYjGd3OHsJQQg"),
+			pwd:        password,
+			wantOk:     true,
+			wantRehash: true,
+		},
+		{
+			name:       "pbkdf2-sha256",
+			encoded:    []byte("$pbkdf2-sha256$6400$0ZrzXitFSGltTQnBWOsdAw$Y11AchqV4b0sUisdZd0Xr97KWoymNE0LNNrnEgY4H9M"),
+			pwd:        password,
+			wantOk:     true,
+			wantRehash: true,
+		},
+		{
+			name:       "pbkdf2-sha512",
+			encoded:    []byte("$pbkdf2-sha512$25000$c2FsdHNhbHRzYWx0c2FsdA$EkdKHGe4sOjpcyqUxy0aCmgL/1yGsJKsXejSYKXhLRsX414emkfeDL2hb.MRorp2fvhEuJxaG4k7DcKp2EdJQA"),
+			pwd:        password,
+			wantOk:     true,
+			wantRehash: true,
+		},
+		{
+			name:    "pbkdf2-sha256 mismatch",
+			encoded: []byte("$pbkdf2-sha256$6400$0ZrzXitFSGltTQnBWOsdAw$Y11AchqV4b0sUisdZd0Xr97KWoymNE0LNNrnEgY4H9M"),
+			pwd:     []byte("wrong"),
+		},
+		{
+			name:    "pbkdf2-sha256 too many rounds",
+			encoded: []byte("$pbkdf2-sha256$2147483647$0ZrzXitFSGltTQnBWOsdAw$Y11AchqV4b0sUisdZd0Xr97KWoymNE0LNNrnEgY4H9M"),
+			pwd:     password,
+			wantErr: argon2.ErrDecodingFail,
+		},
+		{
+			name:    "pbkdf2-sha256 key too long",
+			encoded: []byte("$pbkdf2-sha256$6400$0ZrzXitFSGltTQnBWOsdAw$AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"),
+			pwd:     password,
+			wantErr: argon2.ErrDecodingFail,
+		},
+		{
+			name:    "pbkdf2-sha256 missing hash",
+			encoded: []byte("$pbkdf2-sha256$6400$0ZrzXitFSGltTQnBWOsdAw"),
+			pwd:     password,
+			wantErr: argon2.ErrDecodingFail,
+		},
+		{
+			name:    "unknown format",
+			encoded: []byte("$1$saltsalt$2vnaRpHa6Jxjz5n83ok8Z0"),
+			pwd:     password,
+			wantErr: argon2.ErrIncorrectType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, rehash, err := config.VerifyAny(tt.pwd, tt.encoded)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyAny() error = %v, want %v", err, tt.wantErr)
+			}
+			if ok != tt.wantOk {
+				t.Errorf("VerifyAny() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if rehash != tt.wantRehash {
+				t.Errorf("VerifyAny() rehash = %v, want %v", rehash, tt.wantRehash)
+			}
+		})
+	}
+}
+
+func TestRegisterVerifier(t *testing.T) {
+	const prefix = "{PLAIN}"
+	encoded := []byte(prefix + string(password))
+
+	_, _, err := config.VerifyAny(password, encoded)
+	if !errors.Is(err, argon2.ErrIncorrectType) {
+		t.Fatalf("VerifyAny() error = %v, want %v", err, argon2.ErrIncorrectType)
+	}
+
+	argon2.RegisterVerifier(prefix, argon2.VerifierFunc(func(pwd, encoded []byte) (bool, error) {
+		return bytes.Equal(pwd, encoded[len(prefix):]), nil
+	}))
+	t.Cleanup(func() { argon2.RegisterVerifier(prefix, nil) })
+
+	ok, rehash, err := config.VerifyAny(password, encoded)
+	mustBeFalsey(t, "err", err)
+	if !ok || !rehash {
+		t.Errorf("VerifyAny() = %v, %v, want true, true", ok, rehash)
+	}
+}
@@ -0,0 +1,158 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package luks2 converts between the Argon2 key derivation function (KDF)
+// objects of LUKS2 keyslots and argon2.Config, deriving the keys which
+// encrypt the keyslot areas.
+//
+// Refer: https://gitlab.com/cryptsetup/LUKS2-docs
+package luks2
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/matthewhartstonge/argon2"
+)
+
+// KDF types.
+const (
+	TypeArgon2i  = "argon2i"
+	TypeArgon2id = "argon2id"
+)
+
+// KDF is the "kdf" object of a keyslot.
+type KDF struct {
+	// Type is TypeArgon2i or TypeArgon2id. Other KDFs, such as "pbkdf2",
+	// can be unmarshalled, but not converted to an argon2.Config.
+	Type string `json:"type"`
+	// Time is the number of passes.
+	Time uint32 `json:"time"`
+	// Memory is the memory size in kibibytes.
+	Memory uint32 `json:"memory"`
+	// CPUs is the number of lanes, which are also computed on as many
+	// threads.
+	CPUs uint32 `json:"cpus"`
+	// Salt is encoded using padded standard base64.
+	Salt []byte `json:"salt"`
+}
+
+// Keyslot is a keyslot of the LUKS2 JSON metadata, limited to the fields
+// needed to derive its key.
+type Keyslot struct {
+	Type string `json:"type"`
+	// KeySize is the length of the volume key stored in the keyslot.
+	KeySize uint32 `json:"key_size"`
+	Area    struct {
+		Type       string `json:"type"`
+		Encryption string `json:"encryption"`
+		// KeySize is the length of the key encrypting the area, which
+		// is derived by the KDF.
+		KeySize uint32 `json:"key_size"`
+	} `json:"area"`
+	KDF KDF `json:"kdf"`
+}
+
+// ParseKeyslots returns the keyslots of LUKS2 JSON metadata, keyed by their
+// number, e.g. "0".
+func ParseKeyslots(metadata []byte) (map[string]Keyslot, error) {
+	var header struct {
+		Keyslots map[string]Keyslot `json:"keyslots"`
+	}
+	if err := json.Unmarshal(metadata, &header); err != nil {
+		return nil, err
+	}
+	return header.Keyslots, nil
+}
+
+// DeriveKey derives the key encrypting the keyslot area from `passphrase`.
+func (k Keyslot) DeriveKey(passphrase []byte) ([]byte, error) {
+	return k.KDF.DeriveKey(passphrase, k.Area.KeySize)
+}
+
+// New returns the KDF of `c`, which must be an argon2i or argon2id version
+// 1.3 config, with a new salt of c.SaltLength bytes. cryptsetup uses 32 byte
+// salts.
+func New(c argon2.Config) (KDF, error) {
+	k := KDF{
+		Time:   c.TimeCost,
+		Memory: c.MemoryCost,
+		CPUs:   uint32(c.Parallelism),
+	}
+
+	switch {
+	case c.Mode == argon2.ModeArgon2i && c.Version == argon2.Version13:
+		k.Type = TypeArgon2i
+	case c.Mode == argon2.ModeArgon2id && c.Version == argon2.Version13:
+		k.Type = TypeArgon2id
+	default:
+		return KDF{}, argon2.ErrIncorrectType
+	}
+	if c.SaltLength == 0 {
+		return KDF{}, argon2.ErrSaltTooShort
+	}
+
+	salt, err := c.NewSalt()
+	if err != nil {
+		return KDF{}, err
+	}
+	k.Salt = salt
+
+	return k, nil
+}
+
+// Config returns the argon2.Config deriving keys of keySize bytes using `k`.
+func (k KDF) Config(keySize uint32) (argon2.Config, error) {
+	var mode argon2.Mode
+	switch k.Type {
+	case TypeArgon2i:
+		mode = argon2.ModeArgon2i
+	case TypeArgon2id:
+		mode = argon2.ModeArgon2id
+	default:
+		return argon2.Config{}, argon2.ErrIncorrectType
+	}
+
+	switch {
+	case len(k.Salt) == 0:
+		return argon2.Config{}, argon2.ErrSaltTooShort
+	case k.CPUs > math.MaxUint8:
+		return argon2.Config{}, argon2.ErrLanesTooMany
+	}
+
+	return argon2.Config{
+		HashLength:  keySize,
+		SaltLength:  uint32(len(k.Salt)),
+		TimeCost:    k.Time,
+		MemoryCost:  k.Memory,
+		Parallelism: uint8(k.CPUs),
+		Mode:        mode,
+		Version:     argon2.Version13,
+	}, nil
+}
+
+// DeriveKey derives a key of keySize bytes from `passphrase`.
+func (k KDF) DeriveKey(passphrase []byte, keySize uint32) ([]byte, error) {
+	c, err := k.Config(keySize)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := c.Hash(passphrase, k.Salt)
+	if err != nil {
+		return nil, err
+	}
+	return r.Hash, nil
+}
@@ -0,0 +1,210 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package luks2_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/matthewhartstonge/argon2"
+	"github.com/matthewhartstonge/argon2/luks2"
+)
+
+// keyslotVectors are the keyslots of testdata/header.json, the JSON metadata
+// of a header created by libcryptsetup. The keys were checked to decrypt the
+// volume key from the keyslot areas.
+var keyslotVectors = []struct {
+	keyslot    string
+	passphrase string
+	wantType   string
+	want       string
+}{
+	{
+		keyslot:    "0",
+		passphrase: "correct horse battery staple",
+		wantType:   luks2.TypeArgon2id,
+		want:       "a4622eeefb8a50d50f52f17b577c73fa5c464fc155d5f1faf4d67099b413e6fd0ec2f6df027136617e8de790fe9d337ce1202467810434bb4f9b8a219ae64664",
+	},
+	{
+		keyslot:    "1",
+		passphrase: "hunter2",
+		wantType:   luks2.TypeArgon2i,
+		want:       "f3f20f1094b5ed8e11c9117a4345210e8304d9053cc098e45ea10ebb6b09db8fcfc260be0f36964516cb6c146228af7be2bd7935814f478089bd9fe872fbf560",
+	},
+}
+
+// headerKeyslot returns keyslot `id` of testdata/header.json.
+func headerKeyslot(t *testing.T, id string) luks2.Keyslot {
+	t.Helper()
+
+	metadata, err := os.ReadFile("testdata/header.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyslots, err := luks2.ParseKeyslots(metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks, ok := keyslots[id]
+	if !ok {
+		t.Fatalf("keyslot %s not found", id)
+	}
+	return ks
+}
+
+func TestDeriveKey(t *testing.T) {
+	for _, tt := range keyslotVectors {
+		t.Run(tt.keyslot, func(t *testing.T) {
+			ks := headerKeyslot(t, tt.keyslot)
+			if ks.KDF.Type != tt.wantType {
+				t.Errorf("KDF.Type = %s, want %s", ks.KDF.Type, tt.wantType)
+			}
+
+			key, err := ks.DeriveKey([]byte(tt.passphrase))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hex.EncodeToString(key); got != tt.want {
+				t.Errorf("DeriveKey() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKDFJSON(t *testing.T) {
+	want := `{"type":"argon2id","time":4,"memory":1024,"cpus":1,"salt":"IuA2foTS+/K1WN30vLuvqpli+tOGN1h7dX/qoIaMP8E="}`
+
+	var k luks2.KDF
+	if err := json.Unmarshal([]byte(want), &k); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := json.Marshal(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("json.Marshal() = %s, want %s", got, want)
+	}
+}
+
+// TestNew creates the KDFs of the keyslots from their costs and salts.
+func TestNew(t *testing.T) {
+	for _, tt := range keyslotVectors {
+		t.Run(tt.keyslot, func(t *testing.T) {
+			ks := headerKeyslot(t, tt.keyslot)
+
+			c := argon2.RecommendedDefaults()
+			if tt.wantType == luks2.TypeArgon2i {
+				c.Mode = argon2.ModeArgon2i
+			}
+			c.TimeCost = ks.KDF.Time
+			c.MemoryCost = ks.KDF.Memory
+			c.Parallelism = uint8(ks.KDF.CPUs)
+			c.SaltLength = 32
+			c.Options = &argon2.Options{Rand: bytes.NewReader(ks.KDF.Salt)}
+
+			k, err := luks2.New(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(k, ks.KDF) {
+				t.Errorf("New() = %+v, want %+v", k, ks.KDF)
+			}
+
+			key, err := k.DeriveKey([]byte(tt.passphrase), ks.Area.KeySize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hex.EncodeToString(key); got != tt.want {
+				t.Errorf("DeriveKey() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewError(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(c *argon2.Config)
+		wantErr error
+	}{
+		{
+			name:    "argon2d",
+			mutate:  func(c *argon2.Config) { c.Mode = argon2.ModeArgon2d },
+			wantErr: argon2.ErrIncorrectType,
+		},
+		{
+			name:    "version 1.0",
+			mutate:  func(c *argon2.Config) { c.Version = argon2.Version10 },
+			wantErr: argon2.ErrIncorrectType,
+		},
+		{
+			name:    "no salt",
+			mutate:  func(c *argon2.Config) { c.SaltLength = 0 },
+			wantErr: argon2.ErrSaltTooShort,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := argon2.RecommendedDefaults()
+			tt.mutate(&c)
+			if _, err := luks2.New(c); !errors.Is(err, tt.wantErr) {
+				t.Errorf("New() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigError(t *testing.T) {
+	salt := bytes.Repeat([]byte{0xaa}, 32)
+
+	tests := []struct {
+		name    string
+		k       luks2.KDF
+		wantErr error
+	}{
+		{
+			name:    "pbkdf2",
+			k:       luks2.KDF{Type: "pbkdf2", Salt: salt},
+			wantErr: argon2.ErrIncorrectType,
+		},
+		{
+			name:    "no salt",
+			k:       luks2.KDF{Type: luks2.TypeArgon2id, Time: 4, Memory: 1024, CPUs: 1},
+			wantErr: argon2.ErrSaltTooShort,
+		},
+		{
+			name:    "too many cpus",
+			k:       luks2.KDF{Type: luks2.TypeArgon2id, Time: 4, Memory: 1024, CPUs: 256, Salt: salt},
+			wantErr: argon2.ErrLanesTooMany,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.k.Config(64); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Config() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package argon2
+
+// MarshalText implements encoding.TextMarshaler by returning the encoded
+// argon2 representation, so a Raw can be embedded directly in JSON, YAML or
+// any other text based document.
+func (raw Raw) MarshalText() ([]byte, error) {
+	if raw.Config.Mode.String() == "unknown" {
+		return nil, ErrIncorrectType
+	}
+	return raw.Encode(), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. See Decode.
+func (raw *Raw) UnmarshalText(text []byte) error {
+	r, err := Decode(text)
+	if err != nil {
+		return err
+	}
+	*raw = r
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, returning the compact
+// binary representation of raw suitable for gob or other binary caches. See
+// EncodeBinary.
+func (raw Raw) MarshalBinary() ([]byte, error) {
+	return raw.EncodeBinary()
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. See DecodeBinary.
+func (raw *Raw) UnmarshalBinary(data []byte) error {
+	r, err := DecodeBinary(data)
+	if err != nil {
+		return err
+	}
+	*raw = r
+	return nil
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package argon2_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/matthewhartstonge/argon2"
+)
+
+func TestRawMarshalJSON(t *testing.T) {
+	r, err := argon2.Decode(expectedEncoded)
+	mustBeFalsey(t, "err", err)
+
+	doc := struct {
+		Hash argon2.Raw `json:"hash"`
+	}{Hash: r}
+
+	enc, err := json.Marshal(doc)
+	mustBeFalsey(t, "err", err)
+
+	want := `{"hash":"` + string(expectedEncoded) + `"}`
+	if string(enc) != want {
+		t.Errorf("json.Marshal() = %s, want %s", enc, want)
+	}
+
+	doc.Hash = argon2.Raw{}
+	err = json.Unmarshal(enc, &doc)
+	mustBeFalsey(t, "err", err)
+
+	if !reflect.DeepEqual(doc.Hash, r) {
+		t.Errorf("json.Unmarshal() = %+v, want %+v", doc.Hash, r)
+	}
+
+	err = json.Unmarshal([]byte(`{"hash":"$2y$10$notargon2"}`), &doc)
+	if !errors.Is(err, argon2.ErrIncorrectType) {
+		t.Errorf("json.Unmarshal() error = %v, want %v", err, argon2.ErrIncorrectType)
+	}
+}
+
+func TestRawMarshalBinary(t *testing.T) {
+	r, err := argon2.Decode(expectedEncoded)
+	mustBeFalsey(t, "err", err)
+
+	var buf bytes.Buffer
+	err = gob.NewEncoder(&buf).Encode(r)
+	mustBeFalsey(t, "err", err)
+
+	var got argon2.Raw
+	err = gob.NewDecoder(&buf).Decode(&got)
+	mustBeFalsey(t, "err", err)
+
+	if !reflect.DeepEqual(got, r) {
+		t.Errorf("gob round trip = %+v, want %+v", got, r)
+	}
+
+	data, err := r.MarshalBinary()
+	mustBeFalsey(t, "err", err)
+
+	if len(data) >= len(expectedEncoded) {
+		t.Errorf("binary encoding (%d bytes) should be smaller than the text encoding (%d bytes)", len(data), len(expectedEncoded))
+	}
+
+	for i := 0; i < len(data); i++ {
+		if err := got.UnmarshalBinary(data[:i]); err == nil {
+			t.Errorf("UnmarshalBinary() of truncated data (%d bytes) should have returned an error", i)
+		}
+	}
+
+	if err := got.UnmarshalBinary(append(data, 0)); !errors.Is(err, argon2.ErrDecodingFail) {
+		t.Errorf("UnmarshalBinary() with trailing data error = %v, want %v", err, argon2.ErrDecodingFail)
+	}
+}
+
+func TestConfigMarshalJSON(t *testing.T) {
+	enc, err := json.Marshal(config)
+	mustBeFalsey(t, "err", err)
+
+	want := `{"HashLength":32,"SaltLength":16,"TimeCost":1,"MemoryCost":32768,"Parallelism":1,"Mode":"Argon2id","Version":"13"}`
+	if string(enc) != want {
+		t.Errorf("json.Marshal() = %s, want %s", enc, want)
+	}
+
+	var got argon2.Config
+	err = json.Unmarshal(enc, &got)
+	mustBeFalsey(t, "err", err)
+
+	if got != config {
+		t.Errorf("json.Unmarshal() = %+v, want %+v", got, config)
+	}
+
+	err = json.Unmarshal([]byte(`{"Mode":"argon2x"}`), &got)
+	if !errors.Is(err, argon2.ErrIncorrectType) {
+		t.Errorf("json.Unmarshal() error = %v, want %v", err, argon2.ErrIncorrectType)
+	}
+
+	_, err = json.Marshal(argon2.Config{Mode: 42, Version: argon2.Version13})
+	if !errors.Is(err, argon2.ErrIncorrectType) {
+		t.Errorf("json.Marshal() error = %v, want %v", err, argon2.ErrIncorrectType)
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    argon2.Mode
+		wantErr error
+	}{
+		{input: "Argon2i", want: argon2.ModeArgon2i},
+		{input: "argon2i", want: argon2.ModeArgon2i},
+		{input: "Argon2id", want: argon2.ModeArgon2id},
+		{input: "ARGON2ID", want: argon2.ModeArgon2id},
+		{input: "argon2d", want: 0},
+		{input: "id", wantErr: argon2.ErrIncorrectType},
+		{input: "unknown", wantErr: argon2.ErrIncorrectType},
+		{input: "", wantErr: argon2.ErrIncorrectType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := argon2.ParseMode(tt.input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseMode() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseMode() = %v, want %v", got, tt.want)
+			}
+			if err == nil && got.String() != "unknown" {
+				if m, _ := argon2.ParseMode(got.String()); m != got {
+					t.Errorf("ParseMode(%q) = %v, want %v", got.String(), m, got)
+				}
+			}
+		})
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    argon2.Version
+		wantErr error
+	}{
+		{input: "10", want: argon2.Version10},
+		{input: "16", want: argon2.Version10},
+		{input: "0x10", want: argon2.Version10},
+		{input: "1.0", want: argon2.Version10},
+		{input: "13", want: argon2.Version13},
+		{input: "19", want: argon2.Version13},
+		{input: "0x13", want: argon2.Version13},
+		{input: "1.3", want: argon2.Version13},
+		{input: "12", wantErr: argon2.ErrIncorrectType},
+		{input: "", wantErr: argon2.ErrIncorrectType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := argon2.ParseVersion(tt.input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseVersion() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseVersion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
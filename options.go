@@ -0,0 +1,86 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package argon2
+
+import (
+	"crypto/rand"
+	"io"
+)
+
+// Options contains optional hashing inputs which aren't recorded in the
+// encoded hash. See Config.Options.
+type Options struct {
+	// Secret is an optional key, also known as a pepper, mixed into the
+	// hash. It should be stored separately from the hashes, so that a
+	// leaked database alone can't be used to crack them.
+	Secret []byte
+
+	// AssociatedData is optional, additional data bound to the hash.
+	AssociatedData []byte
+
+	// Rand is the source of randomness used to generate salts. It
+	// defaults to crypto/rand.Reader and should only be replaced with a
+	// cryptographically secure source, such as an HSM backed reader, or a
+	// deterministic one in tests.
+	Rand io.Reader
+
+	// StrictVersion10 computes Version10 hashes using version 1.0 of the
+	// algorithm, as the reference implementation does. Otherwise they are
+	// computed as version 1.3, as by earlier releases, so the hashes those
+	// stored keep verifying. Set it to interoperate with implementations
+	// such as KeePass or Keycloak.
+	StrictVersion10 bool
+
+	// Progress, if set, is called each time a segment, the part of a lane
+	// computed between two synchronisation points, has been filled. Calls
+	// are serialised, but may come from different goroutines and block the
+	// computation, so Progress should return quickly.
+	Progress func(Progress)
+}
+
+// Progress reports how far the computation of a hash has got. The memory is
+// filled Config.TimeCost times (passes), each pass in 4 slices, each slice
+// computing one segment per lane.
+type Progress struct {
+	// Pass, Slice and Lane identify the segment which has been filled.
+	Pass, Slice, Lane uint32
+
+	// Completed is the number of segments filled so far, out of Total.
+	Completed, Total uint32
+}
+
+// random returns the reader salts are generated from.
+func (o *Options) random() io.Reader {
+	if o == nil || o.Rand == nil {
+		return rand.Reader
+	}
+	return o.Rand
+}
+
+// version returns the version of the algorithm hashes of version `v` are
+// computed with.
+func (o *Options) version(v Version) Version {
+	if v == Version10 && (o == nil || !o.StrictVersion10) {
+		return Version13
+	}
+	return v
+}
+
+// needsCore reports whether the options are only supported by the
+// in-package Argon2 core.
+func (o *Options) needsCore() bool {
+	return o != nil && (o.Progress != nil || len(o.Secret) > 0 || len(o.AssociatedData) > 0)
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package argon2
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+var (
+	profilesMu sync.RWMutex
+	profiles   = map[string]Config{
+		// RFC 9106 section 4.
+		"rfc9106-first":  RecommendedDefaults(),
+		"rfc9106-second": MemoryConstrainedDefaults(),
+
+		// OWASP Password Storage Cheat Sheet, which considers these
+		// configurations to provide an equal level of defense.
+		"owasp-2023":    profile(19*1024, 2, 1),
+		"owasp-2023-t1": profile(46*1024, 1, 1),
+		"owasp-2023-t3": profile(12*1024, 3, 1),
+		"owasp-2023-t4": profile(9*1024, 4, 1),
+		"owasp-2023-t5": profile(7*1024, 5, 1),
+
+		// libsodium's crypto_pwhash_*_INTERACTIVE, _MODERATE and
+		// _SENSITIVE limits.
+		"libsodium-interactive": profile(64*1024, 2, 1),
+		"libsodium-moderate":    profile(256*1024, 3, 1),
+		"libsodium-sensitive":   profile(1024*1024, 4, 1),
+
+		// PHP's password_hash defaults for PASSWORD_ARGON2ID.
+		"php": profile(64*1024, 4, 1),
+	}
+)
+
+// profile returns an argon2id profile using a 128-bit salt and a 256-bit
+// tag.
+func profile(memory, time uint32, parallelism uint8) Config {
+	return Config{
+		HashLength:  32,
+		SaltLength:  16,
+		TimeCost:    time,
+		MemoryCost:  memory,
+		Parallelism: parallelism,
+		Mode:        ModeArgon2id,
+		Version:     Version13,
+	}
+}
+
+// RegisterProfile makes `c` available to ProfileByName as `name`.
+// Registering a profile for an existing name replaces it.
+//
+// Out of the box the following profiles are available:
+//   - "rfc9106-first" and "rfc9106-second", as returned by
+//     RecommendedDefaults and MemoryConstrainedDefaults.
+//   - "owasp-2023", OWASP's m=19 MiB, t=2, p=1 recommendation, and its
+//     alternatives "owasp-2023-t1" (46 MiB), "owasp-2023-t3" (12 MiB),
+//     "owasp-2023-t4" (9 MiB) and "owasp-2023-t5" (7 MiB).
+//   - "libsodium-interactive", "libsodium-moderate" and
+//     "libsodium-sensitive", libsodium's argon2id limits.
+//   - "php", PHP's argon2id defaults of m=64 MiB, t=4, p=1.
+//
+// All of them use argon2id with a 128-bit salt and a 256-bit tag.
+func RegisterProfile(name string, c Config) {
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+
+	profiles[name] = c
+}
+
+// ProfileByName returns the Config registered as `name`, so configuration
+// can refer to a profile such as "owasp-2023" instead of raw parameters.
+// See RegisterProfile.
+func ProfileByName(name string) (Config, error) {
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+
+	c, ok := profiles[name]
+	if !ok {
+		return Config{}, fmt.Errorf("argon2: unknown profile %q: %w", name, ErrIncorrectType)
+	}
+	return c, nil
+}
+
+// Profiles returns the sorted names of the registered profiles.
+func Profiles() []string {
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package argon2_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/matthewhartstonge/argon2"
+)
+
+func TestProfileByName(t *testing.T) {
+	tests := []struct {
+		name string
+		m, t uint32
+		p    uint8
+	}{
+		{name: "rfc9106-first", m: 2 * 1024 * 1024, t: 1, p: 4},
+		{name: "rfc9106-second", m: 64 * 1024, t: 3, p: 4},
+		{name: "owasp-2023", m: 19456, t: 2, p: 1},
+		{name: "owasp-2023-t1", m: 47104, t: 1, p: 1},
+		{name: "owasp-2023-t3", m: 12288, t: 3, p: 1},
+		{name: "owasp-2023-t4", m: 9216, t: 4, p: 1},
+		{name: "owasp-2023-t5", m: 7168, t: 5, p: 1},
+		{name: "libsodium-interactive", m: 65536, t: 2, p: 1},
+		{name: "libsodium-moderate", m: 262144, t: 3, p: 1},
+		{name: "libsodium-sensitive", m: 1048576, t: 4, p: 1},
+		{name: "php", m: 65536, t: 4, p: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := argon2.ProfileByName(tt.name)
+			if err != nil {
+				t.Fatalf("ProfileByName() error = %v", err)
+			}
+
+			want := argon2.Config{
+				HashLength:  32,
+				SaltLength:  16,
+				TimeCost:    tt.t,
+				MemoryCost:  tt.m,
+				Parallelism: tt.p,
+				Mode:        argon2.ModeArgon2id,
+				Version:     argon2.Version13,
+			}
+			if c != want {
+				t.Errorf("ProfileByName() = %+v, want %+v", c, want)
+			}
+		})
+	}
+}
+
+func TestProfileByNameError(t *testing.T) {
+	_, err := argon2.ProfileByName("owasp")
+	if !errors.Is(err, argon2.ErrIncorrectType) {
+		t.Errorf("ProfileByName() error = %v, want %v", err, argon2.ErrIncorrectType)
+	}
+}
+
+func TestRegisterProfile(t *testing.T) {
+	want := argon2.Config{
+		HashLength:  64,
+		SaltLength:  32,
+		TimeCost:    2,
+		MemoryCost:  32 * 1024,
+		Parallelism: 2,
+		Mode:        argon2.ModeArgon2i,
+		Version:     argon2.Version13,
+	}
+	argon2.RegisterProfile("test-register", want)
+
+	c, err := argon2.ProfileByName("test-register")
+	if err != nil {
+		t.Fatalf("ProfileByName() error = %v", err)
+	}
+	if c != want {
+		t.Errorf("ProfileByName() = %+v, want %+v", c, want)
+	}
+
+	names := argon2.Profiles()
+	if !slices.IsSorted(names) {
+		t.Errorf("Profiles() = %v, want sorted names", names)
+	}
+	for _, name := range []string{"test-register", "owasp-2023", "php", "rfc9106-first"} {
+		if !slices.Contains(names, name) {
+			t.Errorf("Profiles() = %v, missing %q", names, name)
+		}
+	}
+}
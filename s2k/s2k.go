@@ -0,0 +1,189 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package s2k implements the OpenPGP Argon2 String-to-Key (S2K) specifier,
+// type 4, which derives keys using argon2id version 1.3.
+//
+// Refer: https://www.rfc-editor.org/rfc/rfc9580#section-3.7.1.4
+package s2k
+
+import (
+	"math/bits"
+
+	"github.com/matthewhartstonge/argon2"
+)
+
+const (
+	// Type is the S2K specifier type of Argon2.
+	Type = 4
+	// SaltLength is the length of the salt.
+	SaltLength = 16
+	// Length is the length of an encoded specifier, including the type.
+	Length = 1 + SaltLength + 3
+	// MaxEncodedMemory is the largest encoded memory size, 2^31 KiB.
+	MaxEncodedMemory = 31
+)
+
+// Specifier is an Argon2 S2K specifier.
+type Specifier struct {
+	Salt [SaltLength]byte
+	// Time is the number of passes, t.
+	Time uint8
+	// Parallelism is the number of lanes, p.
+	Parallelism uint8
+	// EncodedMemory is the base 2 logarithm of the memory size in
+	// kibibytes, encoded_m.
+	EncodedMemory uint8
+}
+
+// New returns a Specifier using the costs of `c`, which must be an argon2id
+// version 1.3 config, and a new salt. c.SaltLength is ignored, as RFC 9580
+// fixes salts at SaltLength bytes.
+//
+// As only powers of two can be encoded, c.MemoryCost is rounded up to the
+// next power of two.
+func New(c argon2.Config) (Specifier, error) {
+	switch {
+	case c.Mode != argon2.ModeArgon2id || c.Version != argon2.Version13:
+		return Specifier{}, argon2.ErrIncorrectType
+	case c.MemoryCost == 0:
+		return Specifier{}, argon2.ErrMemoryTooLittle
+	case c.TimeCost > 255:
+		return Specifier{}, argon2.ErrTimeTooLarge
+	case c.MemoryCost > 1<<MaxEncodedMemory:
+		return Specifier{}, argon2.ErrMemoryTooMuch
+	}
+
+	s := Specifier{
+		Time:          uint8(c.TimeCost),
+		Parallelism:   c.Parallelism,
+		EncodedMemory: uint8(bits.Len32(c.MemoryCost - 1)),
+	}
+	if err := s.validate(); err != nil {
+		return Specifier{}, err
+	}
+
+	c.SaltLength = SaltLength
+	salt, err := c.NewSalt()
+	if err != nil {
+		return Specifier{}, err
+	}
+	copy(s.Salt[:], salt)
+
+	return s, nil
+}
+
+// Parse parses the specifier at the start of `b`, which is Length bytes long
+// and starts with the Type octet. Any following bytes are ignored.
+func Parse(b []byte) (Specifier, error) {
+	if len(b) < Length {
+		return Specifier{}, argon2.ErrDecodingLengthFail
+	}
+	if b[0] != Type {
+		return Specifier{}, argon2.ErrIncorrectType
+	}
+
+	var s Specifier
+	copy(s.Salt[:], b[1:])
+	s.Time = b[1+SaltLength]
+	s.Parallelism = b[2+SaltLength]
+	s.EncodedMemory = b[3+SaltLength]
+	if err := s.validate(); err != nil {
+		return Specifier{}, err
+	}
+
+	return s, nil
+}
+
+// AppendBinary appends the encoded specifier to `b`.
+func (s Specifier) AppendBinary(b []byte) ([]byte, error) {
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+
+	b = append(b, Type)
+	b = append(b, s.Salt[:]...)
+	return append(b, s.Time, s.Parallelism, s.EncodedMemory), nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, returning the encoded
+// specifier.
+func (s Specifier) MarshalBinary() ([]byte, error) {
+	return s.AppendBinary(make([]byte, 0, Length))
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. Unlike Parse,
+// `data` must only contain the specifier.
+func (s *Specifier) UnmarshalBinary(data []byte) error {
+	if len(data) != Length {
+		return argon2.ErrDecodingLengthFail
+	}
+
+	parsed, err := Parse(data)
+	if err != nil {
+		return err
+	}
+	*s = parsed
+	return nil
+}
+
+// Config returns the argon2.Config deriving keys of keyLen bytes from
+// passphrases, using the salt of `s`.
+func (s Specifier) Config(keyLen uint32) (argon2.Config, error) {
+	if err := s.validate(); err != nil {
+		return argon2.Config{}, err
+	}
+
+	return argon2.Config{
+		HashLength:  keyLen,
+		SaltLength:  SaltLength,
+		TimeCost:    uint32(s.Time),
+		MemoryCost:  1 << s.EncodedMemory,
+		Parallelism: s.Parallelism,
+		Mode:        argon2.ModeArgon2id,
+		Version:     argon2.Version13,
+	}, nil
+}
+
+// DeriveKey derives a key of keyLen bytes, e.g. the key size of the
+// symmetric cipher, from `passphrase`.
+func (s Specifier) DeriveKey(passphrase []byte, keyLen uint32) ([]byte, error) {
+	c, err := s.Config(keyLen)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := c.Hash(passphrase, s.Salt[:])
+	if err != nil {
+		return nil, err
+	}
+	return r.Hash, nil
+}
+
+// validate checks the costs of `s`. As argon2 requires 8*p KiB of memory,
+// the encoded memory size must be at least 3+ceil(log2(p)).
+func (s Specifier) validate() error {
+	switch {
+	case s.Time == 0:
+		return argon2.ErrTimeTooSmall
+	case s.Parallelism == 0:
+		return argon2.ErrLanesTooFew
+	case s.EncodedMemory < 3+uint8(bits.Len8(s.Parallelism-1)):
+		return argon2.ErrMemoryTooLittle
+	case s.EncodedMemory > MaxEncodedMemory:
+		return argon2.ErrMemoryTooMuch
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package s2k_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/matthewhartstonge/argon2"
+	"github.com/matthewhartstonge/argon2/s2k"
+)
+
+// rfc9580Vectors are the version 4 symmetric-key encrypted session key
+// packets of the Argon2 sample messages in RFC 9580 appendix A.
+var rfc9580Vectors = []struct {
+	name       string
+	keyLen     uint32
+	specifier  string
+	esk        string
+	sessionKey string
+}{
+	{
+		name:       "AES-128",
+		keyLen:     16,
+		specifier:  "049c52f83c27f95e50d535440ecdff3136010415",
+		esk:        "9e52fcad22cf3f956542cba794ef840b11",
+		sessionKey: "0701fe16bbacfd1e7b78ef3b865187374f",
+	},
+	{
+		name:       "AES-192",
+		keyLen:     24,
+		specifier:  "04e14cac4715345918a962dca347e143f8010415",
+		esk:        "8732c9daf6b7146f3fa66a483ddfc7fe6768552e5504b2f017",
+		sessionKey: "0827006dae68e509022ce45a14e569e91001c2955af8dfe194",
+	},
+	{
+		name:       "AES-256",
+		keyLen:     32,
+		specifier:  "04b8789520206ff799c6882c4245a6627c010415",
+		esk:        "9d9f65ecab5a81d0a59bd51a43f67a33fe6ba249521a91aeeb6dd899a5decc68fc",
+		sessionKey: "09bbeda55b9aae63dac45d4f49d89dacf4af37fefc13bab2f1f8e18fb74580d8b0",
+	},
+}
+
+// TestRFC9580Vectors derives the key of each sample message from the
+// passphrase "password" and checks it decrypts the session key.
+func TestRFC9580Vectors(t *testing.T) {
+	switch {
+	case testing.Short():
+		t.Skip("the sample messages use 2 GiB of memory")
+	case strconv.IntSize == 32:
+		t.Skip("the sample messages use 2 GiB of memory, which 32-bit platforms can't allocate")
+	}
+
+	for _, tt := range rfc9580Vectors {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := s2k.Parse(mustDecodeHex(t, tt.specifier))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if spec.Time != 1 || spec.Parallelism != 4 || spec.EncodedMemory != 21 {
+				t.Fatalf("Parse() = %+v, want t=1, p=4, m=21", spec)
+			}
+
+			key, err := spec.DeriveKey([]byte("password"), tt.keyLen)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := cfbDecrypt(t, key, mustDecodeHex(t, tt.esk))
+			if want := mustDecodeHex(t, tt.sessionKey); !bytes.Equal(got, want) {
+				t.Errorf("decrypted session key = %x, want %x", got, want)
+			}
+		})
+	}
+}
+
+func TestMarshalBinary(t *testing.T) {
+	for _, tt := range rfc9580Vectors {
+		t.Run(tt.name, func(t *testing.T) {
+			want := mustDecodeHex(t, tt.specifier)
+
+			var spec s2k.Specifier
+			if err := spec.UnmarshalBinary(want); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := spec.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("MarshalBinary() = %x, want %x", got, want)
+			}
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		name      string
+		specifier string
+		wantErr   error
+	}{
+		{
+			name:      "too short",
+			specifier: "049c52f83c27f95e50d535440ecdff31360104",
+			wantErr:   argon2.ErrDecodingLengthFail,
+		},
+		{
+			name:      "iterated and salted",
+			specifier: "039c52f83c27f95e50d535440ecdff3136010415",
+			wantErr:   argon2.ErrIncorrectType,
+		},
+		{
+			name:      "no passes",
+			specifier: "049c52f83c27f95e50d535440ecdff3136000415",
+			wantErr:   argon2.ErrTimeTooSmall,
+		},
+		{
+			name:      "no lanes",
+			specifier: "049c52f83c27f95e50d535440ecdff3136010015",
+			wantErr:   argon2.ErrLanesTooFew,
+		},
+		{
+			name:      "memory below 8 KiB per lane",
+			specifier: "049c52f83c27f95e50d535440ecdff3136010504",
+			wantErr:   argon2.ErrMemoryTooLittle,
+		},
+		{
+			name:      "memory above 2^31 KiB",
+			specifier: "049c52f83c27f95e50d535440ecdff3136010420",
+			wantErr:   argon2.ErrMemoryTooMuch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s2k.Parse(mustDecodeHex(t, tt.specifier)); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Parse() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	var spec s2k.Specifier
+	err := spec.UnmarshalBinary(mustDecodeHex(t, "049c52f83c27f95e50d535440ecdff313601041500"))
+	if !errors.Is(err, argon2.ErrDecodingLengthFail) {
+		t.Errorf("UnmarshalBinary() error = %v, want %v", err, argon2.ErrDecodingLengthFail)
+	}
+}
+
+// TestNew creates the specifiers of the sample messages from their salts.
+func TestNew(t *testing.T) {
+	for _, tt := range rfc9580Vectors {
+		t.Run(tt.name, func(t *testing.T) {
+			want := mustDecodeHex(t, tt.specifier)
+
+			c := argon2.RecommendedDefaults()
+			c.TimeCost = 1
+			c.Parallelism = 4
+			c.MemoryCost = 1<<20 + 1 // rounded up to 2 GiB
+			c.SaltLength = 32        // ignored, salts are always 16 bytes
+			c.Options = &argon2.Options{Rand: bytes.NewReader(want[1 : 1+s2k.SaltLength])}
+
+			spec, err := s2k.New(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := spec.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("New() = %x, want %x", got, want)
+			}
+
+			cfg, err := spec.Config(tt.keyLen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.MemoryCost != 1<<21 || cfg.TimeCost != 1 || cfg.Parallelism != 4 || cfg.HashLength != tt.keyLen {
+				t.Errorf("Config() = %+v, want m=2097152,t=1,p=4 with a %d byte key", cfg, tt.keyLen)
+			}
+		})
+	}
+}
+
+func TestNewError(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(c *argon2.Config)
+		wantErr error
+	}{
+		{
+			name:    "argon2i",
+			mutate:  func(c *argon2.Config) { c.Mode = argon2.ModeArgon2i },
+			wantErr: argon2.ErrIncorrectType,
+		},
+		{
+			name:    "version 1.0",
+			mutate:  func(c *argon2.Config) { c.Version = argon2.Version10 },
+			wantErr: argon2.ErrIncorrectType,
+		},
+		{
+			name:    "too many passes",
+			mutate:  func(c *argon2.Config) { c.TimeCost = 256 },
+			wantErr: argon2.ErrTimeTooLarge,
+		},
+		{
+			name:    "no memory",
+			mutate:  func(c *argon2.Config) { c.MemoryCost = 0 },
+			wantErr: argon2.ErrMemoryTooLittle,
+		},
+		{
+			name:    "too little memory for the lanes",
+			mutate:  func(c *argon2.Config) { c.MemoryCost = 16 },
+			wantErr: argon2.ErrMemoryTooLittle,
+		},
+		{
+			name:    "too much memory",
+			mutate:  func(c *argon2.Config) { c.MemoryCost = 1<<31 + 1 },
+			wantErr: argon2.ErrMemoryTooMuch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := argon2.RecommendedDefaults()
+			tt.mutate(&c)
+			if _, err := s2k.New(c); !errors.Is(err, tt.wantErr) {
+				t.Errorf("New() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// cfbDecrypt decrypts `ciphertext` using AES in CFB mode with a zero IV, as
+// used to encrypt session keys.
+func cfbDecrypt(t *testing.T, key, ciphertext []byte) []byte {
+	t.Helper()
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := make([]byte, len(ciphertext))
+	feedback := make([]byte, block.BlockSize())
+	stream := make([]byte, block.BlockSize())
+	for i := 0; i < len(ciphertext); i += block.BlockSize() {
+		block.Encrypt(stream, feedback)
+		n := copy(feedback, ciphertext[i:])
+		for j := 0; j < n; j++ {
+			plaintext[i+j] = ciphertext[i+j] ^ stream[j]
+		}
+	}
+
+	return plaintext
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,211 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package sodium provides a libsodium crypto_pwhash compatible API on top of
+// argon2, producing byte-identical outputs to libsodium for the same inputs.
+//
+// Costs are given as libsodium's opslimit, the number of passes, and
+// memlimit, the memory in bytes. Hashes always use a single lane.
+//
+// Refer: https://doc.libsodium.org/password_hashing/default_phf
+package sodium
+
+import (
+	"strings"
+
+	"github.com/matthewhartstonge/argon2"
+)
+
+// Alg selects the argon2 variant, like libsodium's crypto_pwhash_ALG_*
+// constants.
+type Alg int
+
+const (
+	// AlgArgon2i13 is argon2i version 1.3.
+	AlgArgon2i13 Alg = 1
+	// AlgArgon2id13 is argon2id version 1.3.
+	AlgArgon2id13 Alg = 2
+	// AlgDefault is the algorithm used by Str.
+	AlgDefault = AlgArgon2id13
+)
+
+const (
+	// SaltBytes is the length of the salt passed to DeriveKey.
+	SaltBytes = 16
+	// StrBytes bounds the length of strings returned by Str, including
+	// libsodium's NUL terminator.
+	StrBytes = 128
+	// BytesMin is the minimum length of keys derived by DeriveKey.
+	BytesMin = 16
+	// hashBytes is the length of hashes stored by Str.
+	hashBytes = 32
+
+	// OpsLimitMin is the minimum opslimit using argon2id.
+	OpsLimitMin = 1
+	// Argon2iOpsLimitMin is the minimum opslimit using argon2i.
+	Argon2iOpsLimitMin = 3
+	// OpsLimitMax is the maximum opslimit.
+	OpsLimitMax = 1<<32 - 1
+	// MemLimitMin is the minimum memlimit, in bytes.
+	MemLimitMin = 8192
+	// MemLimitMax is the maximum memlimit, in bytes.
+	MemLimitMax = (1<<32 - 1) * 1024
+)
+
+// Presets for argon2id, the default algorithm.
+const (
+	OpsLimitInteractive = 2
+	MemLimitInteractive = 64 << 20
+	OpsLimitModerate    = 3
+	MemLimitModerate    = 256 << 20
+	OpsLimitSensitive   = 4
+	MemLimitSensitive   = 1 << 30
+)
+
+// Presets for argon2i.
+const (
+	Argon2iOpsLimitInteractive = 4
+	Argon2iMemLimitInteractive = 32 << 20
+	Argon2iOpsLimitModerate    = 6
+	Argon2iMemLimitModerate    = 128 << 20
+	Argon2iOpsLimitSensitive   = 8
+	Argon2iMemLimitSensitive   = 512 << 20
+)
+
+// Config maps libsodium's opslimit, memlimit and algorithm onto an
+// argon2.Config generating hashes as libsodium's crypto_pwhash_str_alg does.
+func Config(opsLimit, memLimit uint64, alg Alg) (argon2.Config, error) {
+	var (
+		mode   argon2.Mode
+		minOps uint64
+	)
+	switch alg {
+	case AlgArgon2i13:
+		mode, minOps = argon2.ModeArgon2i, Argon2iOpsLimitMin
+	case AlgArgon2id13:
+		mode, minOps = argon2.ModeArgon2id, OpsLimitMin
+	default:
+		return argon2.Config{}, argon2.ErrIncorrectType
+	}
+
+	switch {
+	case opsLimit < minOps:
+		return argon2.Config{}, argon2.ErrTimeTooSmall
+	case opsLimit > OpsLimitMax:
+		return argon2.Config{}, argon2.ErrTimeTooLarge
+	case memLimit < MemLimitMin:
+		return argon2.Config{}, argon2.ErrMemoryTooLittle
+	case memLimit > MemLimitMax:
+		return argon2.Config{}, argon2.ErrMemoryTooMuch
+	}
+
+	return argon2.Config{
+		HashLength:  hashBytes,
+		SaltLength:  SaltBytes,
+		TimeCost:    uint32(opsLimit),
+		MemoryCost:  uint32(memLimit / 1024),
+		Parallelism: 1,
+		Mode:        mode,
+		Version:     argon2.Version13,
+	}, nil
+}
+
+// DeriveKey derives a key of keyLen bytes from passwd and a SaltBytes long
+// salt, like libsodium's crypto_pwhash.
+func DeriveKey(keyLen uint32, passwd, salt []byte, opsLimit, memLimit uint64, alg Alg) ([]byte, error) {
+	switch {
+	case keyLen < BytesMin:
+		return nil, argon2.ErrOutputTooShort
+	case len(salt) < SaltBytes:
+		return nil, argon2.ErrSaltTooShort
+	case len(salt) > SaltBytes:
+		return nil, argon2.ErrSaltTooLong
+	}
+
+	c, err := Config(opsLimit, memLimit, alg)
+	if err != nil {
+		return nil, err
+	}
+	c.HashLength = keyLen
+
+	r, err := c.Hash(passwd, salt)
+	if err != nil {
+		return nil, err
+	}
+	return r.Hash, nil
+}
+
+// Str returns an encoded argon2id hash of passwd using a random salt, like
+// libsodium's crypto_pwhash_str.
+func Str(passwd []byte, opsLimit, memLimit uint64) (string, error) {
+	return StrAlg(passwd, opsLimit, memLimit, AlgDefault)
+}
+
+// StrAlg works like Str using the given algorithm, like libsodium's
+// crypto_pwhash_str_alg.
+func StrAlg(passwd []byte, opsLimit, memLimit uint64, alg Alg) (string, error) {
+	c, err := Config(opsLimit, memLimit, alg)
+	if err != nil {
+		return "", err
+	}
+
+	enc, err := c.HashEncoded(passwd)
+	if err != nil {
+		return "", err
+	}
+	return string(enc), nil
+}
+
+// StrVerify returns true if passwd matches str, a hash as returned by Str,
+// like libsodium's crypto_pwhash_str_verify.
+func StrVerify(str string, passwd []byte) (bool, error) {
+	r, err := decode(str)
+	if err != nil {
+		return false, err
+	}
+	return r.Verify(passwd)
+}
+
+// StrNeedsRehash reports whether str, a hash as returned by Str, was
+// computed with a different opslimit or memlimit, like libsodium's
+// crypto_pwhash_str_needs_rehash. As in libsodium, the algorithm isn't
+// compared.
+func StrNeedsRehash(str string, opsLimit, memLimit uint64) (bool, error) {
+	r, err := decode(str)
+	if err != nil {
+		return false, err
+	}
+
+	c := r.Config
+	return uint64(c.TimeCost) != opsLimit || uint64(c.MemoryCost) != memLimit/1024, nil
+}
+
+// decode decodes the hashes accepted by libsodium, which are limited to
+// argon2i and argon2id version 1.3 strings shorter than StrBytes.
+func decode(str string) (argon2.Raw, error) {
+	if len(str) >= StrBytes || strings.IndexByte(str, 0) >= 0 {
+		return argon2.Raw{}, argon2.ErrDecodingLengthFail
+	}
+
+	r, err := argon2.Decode([]byte(str))
+	if err != nil {
+		return argon2.Raw{}, err
+	}
+	if (r.Config.Mode != argon2.ModeArgon2i && r.Config.Mode != argon2.ModeArgon2id) ||
+		r.Config.Version != argon2.Version13 {
+		return argon2.Raw{}, argon2.ErrIncorrectType
+	}
+	return r, nil
+}
@@ -0,0 +1,208 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package sodium_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/matthewhartstonge/argon2"
+	"github.com/matthewhartstonge/argon2/sodium"
+)
+
+var (
+	passwd = []byte("Correct Horse Battery Staple")
+	salt   = []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+)
+
+// TestDeriveKey checks DeriveKey against keys derived by libsodium's
+// crypto_pwhash.
+func TestDeriveKey(t *testing.T) {
+	tests := []struct {
+		alg      sodium.Alg
+		opsLimit uint64
+		memLimit uint64
+		want     string
+	}{
+		{alg: sodium.AlgArgon2i13, opsLimit: 3, memLimit: 8192, want: "1102b8ec6a1e482b178e8a8f46330f34"},
+		{alg: sodium.AlgArgon2i13, opsLimit: 3, memLimit: 65536, want: "d106e3a0e70e0d1414e59ecbb5dd0dfc0031b5589e13572ab6118fc02106df1d"},
+		{alg: sodium.AlgArgon2i13, opsLimit: 4, memLimit: 1 << 20, want: "e7d30faac1e0c9e9b8e7508273deaf2159dec98312cee1e5f6c1d2e29e45799a1ed07691fc17c43ab0b2138d17d403c661ce0ad354da2992d727118cca95336c"},
+		{alg: sodium.AlgArgon2i13, opsLimit: 5, memLimit: 100000, want: "5d93837d3de3cb065853fc5e6082063e85a3cd3d7c66cc545d6683e6feac3a42"},
+		{alg: sodium.AlgArgon2i13, opsLimit: sodium.Argon2iOpsLimitInteractive, memLimit: sodium.Argon2iMemLimitInteractive, want: "0dc6f69f7bd34f29cfdb4ca001aed7c75fcdaac63e004267e212f4b9058b5fca"},
+		{alg: sodium.AlgArgon2id13, opsLimit: 3, memLimit: 8192, want: "f07d217028b9694f550c8706ef1fb7de"},
+		{alg: sodium.AlgArgon2id13, opsLimit: 3, memLimit: 65536, want: "fc59d0cb4729db95cbdaa19eb72d4359394f713b107721561ed6461321cf4dfc"},
+		{alg: sodium.AlgArgon2id13, opsLimit: 4, memLimit: 1 << 20, want: "2c9aaf73a1140232ab143243bc607c0f2fa60ffb2f3cc9a164a5138e0e45ca72227853a1270bf839230e729544f0f03a13869d42db64b7d0e7199d5533aeb0ce"},
+		{alg: sodium.AlgArgon2id13, opsLimit: 5, memLimit: 100000, want: "bdb868d20fe96c8fb506edec939519f59431fef7f46aea3893958ffb7b271c22"},
+		{alg: sodium.AlgArgon2id13, opsLimit: sodium.OpsLimitInteractive, memLimit: sodium.MemLimitInteractive, want: "f9e76cb3fd76cf8850abfe0744753fcfe9e6f28358c9894067881bf0c824411b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("alg=%d,ops=%d,mem=%d", tt.alg, tt.opsLimit, tt.memLimit), func(t *testing.T) {
+			key, err := sodium.DeriveKey(uint32(len(tt.want)/2), passwd, salt, tt.opsLimit, tt.memLimit, tt.alg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hex.EncodeToString(key); got != tt.want {
+				t.Errorf("DeriveKey() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeriveKeyError(t *testing.T) {
+	tests := []struct {
+		name     string
+		keyLen   uint32
+		salt     []byte
+		opsLimit uint64
+		memLimit uint64
+		alg      sodium.Alg
+		wantErr  error
+	}{
+		{name: "key too short", keyLen: 15, salt: salt, opsLimit: 3, memLimit: 65536, alg: sodium.AlgDefault, wantErr: argon2.ErrOutputTooShort},
+		{name: "salt too short", keyLen: 32, salt: salt[:15], opsLimit: 3, memLimit: 65536, alg: sodium.AlgDefault, wantErr: argon2.ErrSaltTooShort},
+		{name: "salt too long", keyLen: 32, salt: append(salt, 0), opsLimit: 3, memLimit: 65536, alg: sodium.AlgDefault, wantErr: argon2.ErrSaltTooLong},
+		{name: "argon2id opslimit too small", keyLen: 32, salt: salt, opsLimit: 0, memLimit: 65536, alg: sodium.AlgArgon2id13, wantErr: argon2.ErrTimeTooSmall},
+		{name: "argon2i opslimit too small", keyLen: 32, salt: salt, opsLimit: 2, memLimit: 65536, alg: sodium.AlgArgon2i13, wantErr: argon2.ErrTimeTooSmall},
+		{name: "opslimit too large", keyLen: 32, salt: salt, opsLimit: 1 << 32, memLimit: 65536, alg: sodium.AlgDefault, wantErr: argon2.ErrTimeTooLarge},
+		{name: "memlimit too small", keyLen: 32, salt: salt, opsLimit: 3, memLimit: 8191, alg: sodium.AlgDefault, wantErr: argon2.ErrMemoryTooLittle},
+		{name: "memlimit too large", keyLen: 32, salt: salt, opsLimit: 3, memLimit: 1 << 42, alg: sodium.AlgDefault, wantErr: argon2.ErrMemoryTooMuch},
+		{name: "unknown alg", keyLen: 32, salt: salt, opsLimit: 3, memLimit: 65536, alg: 3, wantErr: argon2.ErrIncorrectType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := sodium.DeriveKey(tt.keyLen, passwd, tt.salt, tt.opsLimit, tt.memLimit, tt.alg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStr(t *testing.T) {
+	str, err := sodium.Str([]byte("password"), 2, 65536)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(str, "$argon2id$v=19$m=64,t=2,p=1$") || len(str) >= sodium.StrBytes {
+		t.Errorf("Str() = %s, want a libsodium style argon2id hash", str)
+	}
+
+	ok, err := sodium.StrVerify(str, []byte("password"))
+	if err != nil || !ok {
+		t.Errorf("StrVerify() = %v, %v, want true, nil", ok, err)
+	}
+
+	str, err = sodium.StrAlg([]byte("password"), 3, 65536, sodium.AlgArgon2i13)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(str, "$argon2i$v=19$m=64,t=3,p=1$") {
+		t.Errorf("StrAlg() = %s, want a libsodium style argon2i hash", str)
+	}
+}
+
+func TestStrVerify(t *testing.T) {
+	tests := []struct {
+		name    string
+		str     string
+		want    bool
+		wantErr error
+	}{
+		{
+			name: "libsodium argon2id",
+			str:  "$argon2id$v=19$m=64,t=2,p=1$UBZ0Cppt28nF95FJEJFKig$lyFEq20OeHVVj1jIWOEC7dNDnssok6ndWq+mvzh2tUM",
+			want: true,
+		},
+		{
+			name: "libsodium argon2i",
+			str:  "$argon2i$v=19$m=64,t=3,p=1$6/KwyTmBd3whQpTyL3UN4A$de2Iv6xq0IexDrGnBYKog1td36+B59+N61nN6+/GIXY",
+			want: true,
+		},
+		{
+			name: "multiple lanes",
+			str:  "$argon2id$v=19$m=256,t=3,p=4$c29tZXNhbHRzb21lc2FsdA$OudWdfwYdYZlJsj5MMtA1v51HsC+pa0wUrr8pTaTc7M",
+			want: true,
+		},
+		{
+			name: "wrong password",
+			str:  "$argon2id$v=19$m=64,t=2,p=1$UBZ0Cppt28nF95FJEJFKig$AAAAq20OeHVVj1jIWOEC7dNDnssok6ndWq+mvzh2tUM",
+			want: false,
+		},
+		{
+			name:    "argon2d",
+			str:     "$argon2d$v=19$m=64,t=1,p=1$c29tZXNhbHRzb21lc2FsdA$PBsbEJvaW4plqqVPMlnaWP2uu+6nkQxszidyn4EQxKw",
+			wantErr: argon2.ErrIncorrectType,
+		},
+		{
+			name:    "version 1.0",
+			str:     "$argon2i$v=16$m=64,t=1,p=1$c29tZXNhbHRzb21lc2FsdA$A1Nfp0KEdbPQaAokRMNb/x1cePp/3qHN7LIF5R2OojM",
+			wantErr: argon2.ErrIncorrectType,
+		},
+		{
+			name:    "too long",
+			str:     "$argon2id$v=19$m=64,t=2,p=1$UBZ0Cppt28nF95FJEJFKig$" + strings.Repeat("A", 86),
+			wantErr: argon2.ErrDecodingLengthFail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := sodium.StrVerify(tt.str, []byte("password"))
+			if ok != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Errorf("StrVerify() = %v, %v, want %v, %v", ok, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestStrNeedsRehash checks StrNeedsRehash against libsodium's
+// crypto_pwhash_str_needs_rehash.
+func TestStrNeedsRehash(t *testing.T) {
+	const (
+		id = "$argon2id$v=19$m=64,t=2,p=1$UBZ0Cppt28nF95FJEJFKig$lyFEq20OeHVVj1jIWOEC7dNDnssok6ndWq+mvzh2tUM"
+		i  = "$argon2i$v=19$m=64,t=3,p=1$6/KwyTmBd3whQpTyL3UN4A$de2Iv6xq0IexDrGnBYKog1td36+B59+N61nN6+/GIXY"
+	)
+
+	tests := []struct {
+		name     string
+		str      string
+		opsLimit uint64
+		memLimit uint64
+		want     bool
+		wantErr  error
+	}{
+		{name: "same limits", str: id, opsLimit: 2, memLimit: 65536, want: false},
+		{name: "different opslimit", str: id, opsLimit: 3, memLimit: 65536, want: true},
+		{name: "memlimit rounded down to KiB", str: id, opsLimit: 2, memLimit: 65536 + 1023, want: false},
+		{name: "different memlimit", str: id, opsLimit: 2, memLimit: 65536 + 1024, want: true},
+		{name: "argon2i", str: i, opsLimit: 3, memLimit: 65536, want: false},
+		{name: "malformed", str: "$argon2id$garbage", opsLimit: 2, memLimit: 65536, wantErr: argon2.ErrDecodingFail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sodium.StrNeedsRehash(tt.str, tt.opsLimit, tt.memLimit)
+			if got != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Errorf("StrNeedsRehash() = %v, %v, want %v, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
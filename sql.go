@@ -0,0 +1,55 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package argon2
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// Scan implements sql.Scanner, decoding an encoded argon2 hash stored in a
+// text or binary database column into raw.
+//
+// NULL values are rejected with ErrDecodingFail. Use sql.Null[Raw] for
+// nullable columns.
+func (raw *Raw) Scan(src any) error {
+	var encoded []byte
+	switch v := src.(type) {
+	case string:
+		encoded = []byte(v)
+	case []byte:
+		// Decode copies the salt and hash out of encoded, so there's no need
+		// to clone the driver owned buffer here.
+		encoded = v
+	case nil:
+		return fmt.Errorf("argon2: cannot scan nil: %w", ErrDecodingFail)
+	default:
+		return fmt.Errorf("argon2: cannot scan %T into Raw: %w", src, ErrDecodingFail)
+	}
+
+	return raw.UnmarshalText(encoded)
+}
+
+// Value implements driver.Valuer, storing raw as its encoded string
+// representation.
+func (raw Raw) Value() (driver.Value, error) {
+	enc, err := raw.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return string(enc), nil
+}
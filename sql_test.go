@@ -0,0 +1,201 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package argon2_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/matthewhartstonge/argon2"
+)
+
+// fakeDriver is an in-memory database/sql driver storing a single "hash"
+// column keyed by id. It understands two statements:
+//
+//	INSERT (id, hash)
+//	SELECT (id) -> hash
+type fakeDriver struct {
+	mu   sync.Mutex
+	rows map[string]driver.Value
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) { return &fakeConn{d: d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{d: c.d, query: query}, nil
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("fakedb: transactions unsupported")
+}
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if s.query != "INSERT" || len(args) != 2 {
+		return nil, errors.New("fakedb: unsupported exec")
+	}
+
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.rows[args[0].(string)] = args[1]
+
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if s.query != "SELECT" || len(args) != 1 {
+		return nil, errors.New("fakedb: unsupported query")
+	}
+
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	v, ok := s.d.rows[args[0].(string)]
+
+	return &fakeRows{value: v, done: !ok}, nil
+}
+
+type fakeRows struct {
+	value driver.Value
+	done  bool
+}
+
+func (r *fakeRows) Columns() []string { return []string{"hash"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.value
+	return nil
+}
+
+var (
+	fakeDB     = &fakeDriver{rows: map[string]driver.Value{}}
+	registerDB sync.Once
+)
+
+func openFakeDB(t *testing.T) *sql.DB {
+	registerDB.Do(func() {
+		sql.Register("argon2-fakedb", fakeDB)
+	})
+
+	db, err := sql.Open("argon2-fakedb", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	return db
+}
+
+func TestRawValue(t *testing.T) {
+	r, err := argon2.Decode(expectedEncoded)
+	mustBeFalsey(t, "err", err)
+
+	db := openFakeDB(t)
+	if _, err := db.Exec("INSERT", "value", r); err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+
+	fakeDB.mu.Lock()
+	stored := fakeDB.rows["value"]
+	fakeDB.mu.Unlock()
+
+	if stored != string(expectedEncoded) {
+		t.Errorf("stored value = %#v, want %q", stored, expectedEncoded)
+	}
+
+	if _, err := db.Exec("INSERT", "invalid", argon2.Raw{Config: argon2.Config{Mode: 42}}); !errors.Is(err, argon2.ErrIncorrectType) {
+		t.Errorf("Exec() error = %v, want %v", err, argon2.ErrIncorrectType)
+	}
+}
+
+func TestRawScan(t *testing.T) {
+	want, err := argon2.Decode(expectedEncoded)
+	mustBeFalsey(t, "err", err)
+
+	tests := []struct {
+		name    string
+		stored  driver.Value
+		wantErr error
+	}{
+		{name: "text column", stored: string(expectedEncoded)},
+		{name: "binary column", stored: append([]byte(nil), expectedEncoded...)},
+		{name: "null", stored: nil, wantErr: argon2.ErrDecodingFail},
+		{name: "non argon2 hash", stored: "$2y$10$B93GqMy3DNkIvyLbsxgtFO", wantErr: argon2.ErrIncorrectType},
+		{name: "corrupt hash", stored: "$argon2id$v=19$m=32768,t=1,p=1$c2FsdHNhbHQ$", wantErr: argon2.ErrDecodingFail},
+		{name: "unsupported type", stored: int64(42), wantErr: argon2.ErrDecodingFail},
+	}
+
+	db := openFakeDB(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeDB.mu.Lock()
+			fakeDB.rows[tt.name] = tt.stored
+			fakeDB.mu.Unlock()
+
+			var got argon2.Raw
+			err := db.QueryRow("SELECT", tt.name).Scan(&got)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Scan() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, want) {
+				t.Errorf("Scan() = %+v, want %+v", got, want)
+			}
+		})
+	}
+
+	// Scanning NULL describes the problem, like other unscannable values.
+	var raw argon2.Raw
+	if err := raw.Scan(nil); err == argon2.ErrDecodingFail || !errors.Is(err, argon2.ErrDecodingFail) {
+		t.Errorf("Scan(nil) error = %v, want a wrapped %v", err, argon2.ErrDecodingFail)
+	}
+}
+
+func TestRawScanNullable(t *testing.T) {
+	db := openFakeDB(t)
+
+	fakeDB.mu.Lock()
+	fakeDB.rows["nullable"] = nil
+	fakeDB.mu.Unlock()
+
+	var got sql.Null[argon2.Raw]
+	if err := db.QueryRow("SELECT", "nullable").Scan(&got); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if got.Valid {
+		t.Errorf("Scan() of NULL should not be valid")
+	}
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package argon2
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// vectorsJSON contains known-answer test vectors for every mode and version,
+// taken from RFC 9106 and computed by the reference implementation.
+//
+//go:embed testdata/vectors.json
+var vectorsJSON []byte
+
+// vector is a known-answer test vector.
+type vector struct {
+	Name           string   `json:"name"`
+	Mode           Mode     `json:"mode"`
+	Version        Version  `json:"version"`
+	Time           uint32   `json:"time"`
+	Memory         uint32   `json:"memory"`
+	Parallelism    uint8    `json:"parallelism"`
+	Password       hexBytes `json:"password"`
+	Salt           hexBytes `json:"salt"`
+	Secret         hexBytes `json:"secret"`
+	AssociatedData hexBytes `json:"associatedData"`
+	Hash           hexBytes `json:"hash"`
+}
+
+// hexBytes is a hex encoded JSON string.
+type hexBytes []byte
+
+func (b *hexBytes) UnmarshalText(text []byte) error {
+	v, err := hex.DecodeString(string(text))
+	*b = v
+	return err
+}
+
+// config returns the Config computing the vector's hash.
+func (v *vector) config() Config {
+	c := Config{
+		HashLength:  uint32(len(v.Hash)),
+		SaltLength:  uint32(len(v.Salt)),
+		TimeCost:    v.Time,
+		MemoryCost:  v.Memory,
+		Parallelism: v.Parallelism,
+		Mode:        v.Mode,
+		Version:     v.Version,
+	}
+	if len(v.Secret) > 0 || len(v.AssociatedData) > 0 || v.Version == Version10 {
+		c.Options = &Options{
+			Secret:          v.Secret,
+			AssociatedData:  v.AssociatedData,
+			StrictVersion10: v.Version == Version10,
+		}
+	}
+	return c
+}
+
+// loadVectors parses the embedded test vectors.
+func loadVectors() ([]vector, error) {
+	var vectors []vector
+	if err := json.Unmarshal(vectorsJSON, &vectors); err != nil {
+		return nil, err
+	}
+	return vectors, nil
+}
+
+// VerifyVectors checks that hashes are computed correctly on this platform,
+// by computing the known-answer test vectors from RFC 9106 and the reference
+// implementation, covering argon2d, argon2i and argon2id, versions 1.0 and
+// 1.3, as well as secrets and associated data.
+//
+// It allocates up to 64 MiB and takes a few seconds, so is best run as part
+// of a test suite, rather than on start up.
+func VerifyVectors() error {
+	vectors, err := loadVectors()
+	if err != nil {
+		return err
+	}
+
+	for _, v := range vectors {
+		c := v.config()
+		r, err := c.Hash(v.Password, v.Salt)
+		if err != nil {
+			return fmt.Errorf("argon2: test vector %q: %w", v.Name, err)
+		}
+		if !bytes.Equal(r.Hash, v.Hash) {
+			return fmt.Errorf("argon2: test vector %q: %w", v.Name, ErrVerifyMismatch)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package argon2
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestVectors(t *testing.T) {
+	vectors, err := loadVectors()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range vectors {
+		threadCounts := []uint8{0}
+		if v.Parallelism > 1 {
+			threadCounts = append(threadCounts, 1)
+		}
+
+		for _, threads := range threadCounts {
+			t.Run(fmt.Sprintf("%s/threads=%d", v.Name, threads), func(t *testing.T) {
+				c := v.config()
+				c.Threads = threads
+
+				r, err := c.Hash(v.Password, v.Salt)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(r.Hash, v.Hash) {
+					t.Errorf("Hash() = %x, want %x", r.Hash, v.Hash)
+				}
+
+				ok, err := r.Verify(v.Password)
+				if err != nil || !ok {
+					t.Errorf("Verify() = %v, %v, want true, nil", ok, err)
+				}
+			})
+		}
+	}
+}
+
+func TestVerifyVectors(t *testing.T) {
+	if err := VerifyVectors(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,256 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package argon2
+
+import (
+	"bytes"
+	"crypto/md5"  //nolint:gosec // required to wrap legacy md5 hashes.
+	"crypto/sha1" //nolint:gosec // required to wrap legacy sha1 hashes.
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"strconv"
+	"sync"
+
+	"golang.org/x/crypto/blowfish"
+)
+
+// Wrapper is a legacy password hashing function whose stored output can be
+// hashed ("wrapped") with argon2, upgrading every legacy hash at once instead
+// of waiting for each user to log in. See Config.HashWrapped.
+type Wrapper interface {
+	// Parse validates a stored legacy hash, returning it in the canonical
+	// form produced by Hash, along with any parameters (e.g. a salt) that
+	// Hash needs to recompute it.
+	Parse(legacy []byte) (canonical, params []byte, err error)
+
+	// Hash recomputes the legacy hash of `pwd` given the params returned by
+	// Parse.
+	Hash(pwd, params []byte) ([]byte, error)
+}
+
+var (
+	wrappersMu sync.RWMutex
+	wrappers   = map[string]Wrapper{
+		"md5":    hexDigestWrapper(md5.New),
+		"sha1":   hexDigestWrapper(sha1.New),
+		"sha256": hexDigestWrapper(sha256.New),
+		"bcrypt": bcryptWrapper{},
+	}
+)
+
+// RegisterWrapper makes a Wrapper available under `name`, which is recorded
+// in the encoded hash and must only contain lowercase letters, digits and
+// '-'. Registering a Wrapper for an existing name replaces it, while a nil
+// Wrapper removes it.
+//
+// Out of the box, unsalted hex encoded "md5", "sha1" and "sha256" digests
+// as well as "bcrypt" hashes can be wrapped.
+func RegisterWrapper(name string, w Wrapper) {
+	if !validWrapName([]byte(name)) {
+		panic("argon2: invalid wrapper name " + strconv.Quote(name))
+	}
+
+	wrappersMu.Lock()
+	defer wrappersMu.Unlock()
+
+	if w == nil {
+		delete(wrappers, name)
+		return
+	}
+	wrappers[name] = w
+}
+
+// lookupWrapper returns the Wrapper registered under `name`.
+func lookupWrapper(name string) (Wrapper, error) {
+	wrappersMu.RLock()
+	defer wrappersMu.RUnlock()
+
+	w, ok := wrappers[name]
+	if !ok {
+		return nil, ErrIncorrectType
+	}
+	return w, nil
+}
+
+// validWrapName reports whether `name` can safely be stored as a PHC
+// parameter value.
+func validWrapName(name []byte) bool {
+	if len(name) == 0 || len(name) > 32 {
+		return false
+	}
+	for _, b := range name {
+		if (b < 'a' || b > 'z') && (b < '0' || b > '9') && b != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// HashWrapped takes a legacy hash, as stored by the Wrapper registered under
+// `wrap`, and returns an argon2 hash of it, recording the wrapper and its
+// parameters so that Raw.Verify and VerifyEncoded re-apply the legacy hash
+// function to the password before verifying it.
+//
+// A salt of Config.SaltLength bytes is generated for you.
+//
+// Note: the extra "wrap" parameters are specific to this library, therefore
+// wrapped hashes can't be verified by other argon2 implementations.
+func (c *Config) HashWrapped(legacy []byte, wrap string) (Raw, error) {
+	w, err := lookupWrapper(wrap)
+	if err != nil {
+		return Raw{}, err
+	}
+
+	canonical, params, err := w.Parse(legacy)
+	if err != nil {
+		return Raw{}, err
+	}
+
+	r, err := c.Hash(canonical, nil)
+	if err != nil {
+		return Raw{}, err
+	}
+	r.Wrap = wrap
+	r.WrapParams = params
+
+	return r, nil
+}
+
+// unwrap re-applies the legacy hash function recorded in raw, if any, to
+// `pwd`.
+func (raw *Raw) unwrap(pwd []byte) ([]byte, error) {
+	if raw.Wrap == "" {
+		return pwd, nil
+	}
+
+	w, err := lookupWrapper(raw.Wrap)
+	if err != nil {
+		return nil, err
+	}
+	return w.Hash(pwd, raw.WrapParams)
+}
+
+// hexDigestWrapper wraps unsalted, hex encoded digests.
+type hexDigestWrapper func() hash.Hash
+
+func (h hexDigestWrapper) Parse(legacy []byte) (canonical, params []byte, err error) {
+	digest := make([]byte, hex.DecodedLen(len(legacy)))
+	n, err := hex.Decode(digest, legacy)
+	if err != nil || n != h().Size() {
+		return nil, nil, ErrDecodingFail
+	}
+
+	// Digests are commonly stored in upper case, so normalise to the
+	// lowercase form returned by Hash.
+	return []byte(hex.EncodeToString(digest)), nil, nil
+}
+
+func (h hexDigestWrapper) Hash(pwd, _ []byte) ([]byte, error) {
+	d := h()
+	d.Write(pwd)
+	return []byte(hex.EncodeToString(d.Sum(nil))), nil
+}
+
+// bcryptEncoding is the non-standard base64 alphabet used by bcrypt.
+var bcryptEncoding = base64.NewEncoding("./ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789").
+	WithPadding(base64.NoPadding)
+
+// bcryptWrapper wraps "$2a$", "$2b$" and "$2y$" bcrypt hashes. x/crypto/bcrypt
+// doesn't support hashing with a given salt, therefore the hash is
+// recomputed based on its blowfish primitives.
+type bcryptWrapper struct{}
+
+const (
+	// bcryptSettingLen is the length of "$2b$<cost>$<22 character salt>".
+	bcryptSettingLen = 29
+	// bcryptHashLen is the length of a complete bcrypt hash.
+	bcryptHashLen = bcryptSettingLen + 31
+)
+
+func (bcryptWrapper) Parse(legacy []byte) (canonical, params []byte, err error) {
+	if len(legacy) != bcryptHashLen {
+		return nil, nil, ErrDecodingFail
+	}
+
+	params = append([]byte(nil), legacy[:bcryptSettingLen]...)
+	if _, _, err := parseBcryptSetting(params); err != nil {
+		return nil, nil, err
+	}
+	if _, err := bcryptEncoding.DecodeString(string(legacy[bcryptSettingLen:])); err != nil {
+		return nil, nil, ErrDecodingFail
+	}
+
+	return append([]byte(nil), legacy...), params, nil
+}
+
+func (bcryptWrapper) Hash(pwd, params []byte) ([]byte, error) {
+	cost, salt, err := parseBcryptSetting(params)
+	if err != nil {
+		return nil, err
+	}
+	if len(pwd) > 72 {
+		return nil, ErrPwdTooLong
+	}
+
+	// The key includes the trailing NUL byte of the C string.
+	key := append(append(make([]byte, 0, len(pwd)+1), pwd...), 0)
+	c, err := blowfish.NewSaltedCipher(key, salt)
+	if err != nil {
+		return nil, ErrDecodingFail
+	}
+	for i := uint64(0); i < 1<<cost; i++ {
+		blowfish.ExpandKey(key, c)
+		blowfish.ExpandKey(salt, c)
+	}
+
+	ctext := []byte("OrpheanBeholderScryDoubt")
+	for i := 0; i < len(ctext); i += 8 {
+		for j := 0; j < 64; j++ {
+			c.Encrypt(ctext[i:i+8], ctext[i:i+8])
+		}
+	}
+
+	// bcrypt only ever encodes the first 23 bytes of the cipher text.
+	out := append(make([]byte, 0, bcryptHashLen), params...)
+	out = bcryptEncoding.AppendEncode(out, ctext[:23])
+
+	return out, nil
+}
+
+// parseBcryptSetting validates a "$2b$<cost>$<22 character salt>" setting,
+// returning its cost and decoded salt.
+func parseBcryptSetting(setting []byte) (cost uint, salt []byte, err error) {
+	if len(setting) != bcryptSettingLen ||
+		!(bytes.HasPrefix(setting, []byte("$2a$")) || bytes.HasPrefix(setting, []byte("$2b$")) || bytes.HasPrefix(setting, []byte("$2y$"))) ||
+		setting[6] != '$' {
+		return 0, nil, ErrDecodingFail
+	}
+
+	n, err := strconv.ParseUint(string(setting[4:6]), 10, 8)
+	if err != nil || n < 4 || n > 31 {
+		return 0, nil, ErrDecodingFail
+	}
+
+	salt, err = bcryptEncoding.DecodeString(string(setting[7:]))
+	if err != nil || len(salt) != 16 {
+		return 0, nil, ErrDecodingFail
+	}
+
+	return uint(n), salt, nil
+}
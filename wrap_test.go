@@ -0,0 +1,210 @@
+/*
+ * Copyright 2022. Matthew Hartstonge <matt@mykro.co.nz>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package argon2_test
+
+import (
+	"bytes"
+	"crypto/md5" //nolint:gosec // testing legacy md5 hashes.
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/matthewhartstonge/argon2"
+)
+
+func TestHashWrapped(t *testing.T) {
+	md5Sum := md5.Sum(password) //nolint:gosec // testing legacy md5 hashes.
+	bcryptHash, err := bcrypt.GenerateFromPassword(password, bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		wrap       string
+		legacy     []byte
+		wantParams bool
+	}{
+		{
+			name:   "md5",
+			wrap:   "md5",
+			legacy: []byte(hex.EncodeToString(md5Sum[:])),
+		},
+		{
+			name:   "upper case md5",
+			wrap:   "md5",
+			legacy: bytes.ToUpper([]byte(hex.EncodeToString(md5Sum[:]))),
+		},
+		{
+			name:       "bcrypt",
+			wrap:       "bcrypt",
+			legacy:     bcryptHash,
+			wantParams: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := config.HashWrapped(tt.legacy, tt.wrap)
+			mustBeFalsey(t, "err", err)
+
+			if r.Wrap != tt.wrap {
+				t.Errorf("r.Wrap = %q, want %q", r.Wrap, tt.wrap)
+			}
+			if (len(r.WrapParams) > 0) != tt.wantParams {
+				t.Errorf("r.WrapParams = %q, want params: %v", r.WrapParams, tt.wantParams)
+			}
+
+			encoded := r.Encode()
+			if !bytes.Contains(encoded, []byte(",p=1,wrap="+tt.wrap)) {
+				t.Errorf("encoded hash %q should record the wrapper", encoded)
+			}
+
+			decoded, err := argon2.Decode(encoded)
+			mustBeFalsey(t, "err", err)
+			if !reflect.DeepEqual(decoded, r) {
+				t.Errorf("Decode() = %+v, want %+v", decoded, r)
+			}
+
+			bin, err := r.EncodeBinary()
+			mustBeFalsey(t, "err", err)
+			decoded, err = argon2.DecodeBinary(bin)
+			mustBeFalsey(t, "err", err)
+			if !reflect.DeepEqual(decoded, r) {
+				t.Errorf("DecodeBinary() = %+v, want %+v", decoded, r)
+			}
+
+			ok, err := argon2.VerifyEncoded(password, encoded)
+			mustBeFalsey(t, "err", err)
+			if !ok {
+				t.Error("VerifyEncoded() should match the password")
+			}
+
+			ok, err = argon2.VerifyEncoded([]byte("wrong"), encoded)
+			mustBeFalsey(t, "err", err)
+			if ok {
+				t.Error("VerifyEncoded() should not match a wrong password")
+			}
+
+			ok, err = argon2.VerifyEncoded(tt.legacy, encoded)
+			mustBeFalsey(t, "err", err)
+			if ok {
+				t.Error("VerifyEncoded() should not match the legacy hash itself")
+			}
+		})
+	}
+}
+
+func TestHashWrappedError(t *testing.T) {
+	tests := []struct {
+		name    string
+		wrap    string
+		legacy  []byte
+		wantErr error
+	}{
+		{
+			name:    "unknown wrapper",
+			wrap:    "md4",
+			legacy:  []byte("5f4dcc3b5aa765d61d8327deb882cf99"),
+			wantErr: argon2.ErrIncorrectType,
+		},
+		{
+			name:    "md5 digest too short",
+			wrap:    "md5",
+			legacy:  []byte("5f4dcc3b5aa765d61d8327deb882cf"),
+			wantErr: argon2.ErrDecodingFail,
+		},
+		{
+			name:    "md5 digest not hex",
+			wrap:    "md5",
+			legacy:  []byte("5f4dcc3b5aa765d61d8327deb882cfzz"),
+			wantErr: argon2.ErrDecodingFail,
+		},
+		{
+			name:    "bcrypt hash truncated",
+			wrap:    "bcrypt",
+			legacy:  []byte("$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhW"),
+			wantErr: argon2.ErrDecodingFail,
+		},
+		{
+			name:    "bcrypt cost out of range",
+			wrap:    "bcrypt",
+			legacy:  []byte("$2a$99$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy"),
+			wantErr: argon2.ErrDecodingFail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := config.HashWrapped(tt.legacy, tt.wrap)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("HashWrapped() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecodeWrapError(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr error
+	}{
+		{
+			name:    "ignores unknown parameters",
+			input:   "$argon2id$v=19$m=65536,t=3,p=4,data=Zm9v$c29tZXNhbHQ$RdescudvJCsgt3ub+b+dWRWJTmaaJObG",
+			wantErr: nil,
+		},
+		{
+			name:    "invalid wrapper name",
+			input:   "$argon2id$v=19$m=65536,t=3,p=4,wrap=MD5$c29tZXNhbHQ$RdescudvJCsgt3ub+b+dWRWJTmaaJObG",
+			wantErr: argon2.ErrDecodingFail,
+		},
+		{
+			name:    "wrapper params without a wrapper",
+			input:   "$argon2id$v=19$m=65536,t=3,p=4,wrapparams=Zm9v$c29tZXNhbHQ$RdescudvJCsgt3ub+b+dWRWJTmaaJObG",
+			wantErr: argon2.ErrDecodingFail,
+		},
+		{
+			name:    "invalid wrapper params",
+			input:   "$argon2id$v=19$m=65536,t=3,p=4,wrap=bcrypt,wrapparams=!!$c29tZXNhbHQ$RdescudvJCsgt3ub+b+dWRWJTmaaJObG",
+			wantErr: argon2.ErrDecodingFail,
+		},
+		{
+			name:    "garbage after parallelism",
+			input:   "$argon2id$v=19$m=65536,t=3,p=4x$c29tZXNhbHQ$RdescudvJCsgt3ub+b+dWRWJTmaaJObG",
+			wantErr: argon2.ErrDecodingFail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := argon2.Decode([]byte(tt.input))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	_, err := argon2.VerifyEncoded(password, []byte("$argon2id$v=19$m=32,t=1,p=1,wrap=md4$c29tZXNhbHQ$RdescudvJCsgt3ub+b+dWRWJTmaaJObG"))
+	if !errors.Is(err, argon2.ErrIncorrectType) {
+		t.Errorf("VerifyEncoded() with an unregistered wrapper error = %v, want %v", err, argon2.ErrIncorrectType)
+	}
+}